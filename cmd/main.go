@@ -4,6 +4,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 var pollInterval = flag.Duration("poll_interval", 1*time.Second, "how often to send metrics")
 var pollIntervalDisabled = flag.Bool("poll_interval_disabled", false, "how often to send metrics")
 var configFile = flag.String("config", "", "path to the config file for this plugin")
+var audit = flag.Bool("audit", false, "check the configuration against the live servers, print a report and exit")
 var err error
 
 func main() {
@@ -36,6 +38,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	// audit the configuration instead of running the plugin
+	if *audit {
+		auditor, ok := shim.Input.(interface{ Audit(io.Writer) error })
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Err: input does not support audit\n")
+			os.Exit(1)
+		}
+
+		if err := auditor.Audit(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Err: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// run the input plugin(s) until stdin closes or we receive a termination signal
 	if err := shim.Run(*pollInterval); err != nil {
 		fmt.Fprintf(os.Stderr, "Err: %s\n", err)
@@ -0,0 +1,111 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// taggedAccumulator is a telegraf.Accumulator adding tags to every emitted metric
+type taggedAccumulator struct {
+	telegraf.Accumulator
+	tags map[string]string
+}
+
+func (a *taggedAccumulator) withTags(tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(tags)+len(a.tags))
+	for k, v := range tags {
+		merged[k] = v
+	}
+	for k, v := range a.tags {
+		merged[k] = v
+	}
+	return merged
+}
+
+// AddFields adds a metric with the accumulator tags
+func (a *taggedAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddFields(measurement, fields, a.withTags(tags), t...)
+}
+
+// AddGauge adds a gauge metric with the accumulator tags
+func (a *taggedAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddGauge(measurement, fields, a.withTags(tags), t...)
+}
+
+// AddCounter adds a counter metric with the accumulator tags
+func (a *taggedAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddCounter(measurement, fields, a.withTags(tags), t...)
+}
+
+// AddSummary adds a summary metric with the accumulator tags
+func (a *taggedAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddSummary(measurement, fields, a.withTags(tags), t...)
+}
+
+// AddHistogram adds a histogram metric with the accumulator tags
+func (a *taggedAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddHistogram(measurement, fields, a.withTags(tags), t...)
+}
+
+// AddMetric adds a metric with the accumulator tags
+func (a *taggedAccumulator) AddMetric(m telegraf.Metric) {
+	for k, v := range a.tags {
+		m.AddTag(k, v)
+	}
+	a.Accumulator.AddMetric(m)
+}
+
+// AddError adds an error prefixed with the accumulator tags
+func (a *taggedAccumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	a.Accumulator.AddError(fmt.Errorf("%v: %s", a.tags, err))
+}
+
+// precisionAccumulator is a telegraf.Accumulator truncating every metric timestamp to the configured precision
+type precisionAccumulator struct {
+	telegraf.Accumulator
+	precision time.Duration
+}
+
+func (a *precisionAccumulator) time(t []time.Time) time.Time {
+	if len(t) > 0 {
+		return t[0].Truncate(a.precision)
+	}
+	return time.Now().Truncate(a.precision)
+}
+
+// AddFields adds a metric with a truncated timestamp
+func (a *precisionAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddFields(measurement, fields, tags, a.time(t))
+}
+
+// AddGauge adds a gauge metric with a truncated timestamp
+func (a *precisionAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddGauge(measurement, fields, tags, a.time(t))
+}
+
+// AddCounter adds a counter metric with a truncated timestamp
+func (a *precisionAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddCounter(measurement, fields, tags, a.time(t))
+}
+
+// AddSummary adds a summary metric with a truncated timestamp
+func (a *precisionAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddSummary(measurement, fields, tags, a.time(t))
+}
+
+// AddHistogram adds a histogram metric with a truncated timestamp
+func (a *precisionAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.Accumulator.AddHistogram(measurement, fields, tags, a.time(t))
+}
+
+// AddMetric adds a metric with a truncated timestamp
+func (a *precisionAccumulator) AddMetric(m telegraf.Metric) {
+	m.SetTime(m.Time().Truncate(a.precision))
+	a.Accumulator.AddMetric(m)
+}
@@ -4,6 +4,7 @@ package bigbluebutton
 import (
 	"bytes"
 	"encoding/xml"
+	"time"
 )
 
 // MetadataStruct is a generic object that contains a Metadata and ParsedMetada
@@ -17,6 +18,12 @@ func (m *MetadataStruct) ParseMetadata() {
 	m.ParsedMetadata = xmlToMap(bytes.NewReader(m.Metadata.Inner))
 }
 
+// DiscardMetadata releases the raw and parsed metadata
+func (m *MetadataStruct) DiscardMetadata() {
+	m.Metadata.Inner = nil
+	m.ParsedMetadata = nil
+}
+
 // ContainsMetadata check if the struct contains the metadata
 func (m *MetadataStruct) ContainsMetadata(md string) bool {
 	_, ok := m.ParsedMetadata[md]
@@ -28,12 +35,21 @@ func (m *MetadataStruct) GetMetadata(md string) string {
 	return m.ParsedMetadata[md]
 }
 
+// APIResponse is a generic BigBlueButton XML api response
+type APIResponse struct {
+	XMLName    xml.Name `xml:"response"`
+	ReturnCode string   `xml:"returncode"`
+	MessageKey string   `xml:"messageKey"`
+}
+
 // MeetingsResponse is BigBlueButton XML global getMeetings api reponse type
 type MeetingsResponse struct {
 	XMLName    xml.Name `xml:"response"`
 	ReturnCode string   `xml:"returncode"`
 	MessageKey string   `xml:"messageKey"`
 	Meetings   Meetings `xml:"meetings"`
+	// ParseErrors is the number of malformed meeting entries skipped while decoding
+	ParseErrors uint64 `xml:"-"`
 }
 
 // RecordingsResponse is BigBlueButton XML global getRecordings api response type
@@ -42,6 +58,8 @@ type RecordingsResponse struct {
 	ReturnCode string     `xml:"returncode"`
 	MessageKey string     `xml:"messageKey"`
 	Recordings Recordings `xml:"recordings"`
+	// Summary holds the recording metrics of the whole response, even when recordings are not kept
+	Summary *Record `xml:"-"`
 }
 
 // Recordings is BigBlueButton XML recordings section
@@ -52,12 +70,37 @@ type Recordings struct {
 
 // Recording is recording response containt information like state, record identifier, ...
 type Recording struct {
-	XMLName   xml.Name `xml:"recording"`
-	RecordID  string   `xml:"recordID"`
-	Published bool     `xml:"published"`
+	XMLName      xml.Name         `xml:"recording"`
+	RecordID     string           `xml:"recordID"`
+	MeetingID    string           `xml:"meetingID"`
+	Published    bool             `xml:"published"`
+	State        string           `xml:"state"`
+	StartTime    uint64           `xml:"startTime"`
+	Size         uint64           `xml:"size"`
+	Participants uint64           `xml:"participants"`
+	Playback     []PlaybackFormat `xml:"playback>format"`
 	MetadataStruct
 }
 
+// PlaybackFormat is a recording playback format
+type PlaybackFormat struct {
+	Type string `xml:"type"`
+	URL  string `xml:"url"`
+	// Length is the playback length in minutes
+	Length uint64 `xml:"length"`
+}
+
+// Length returns the recording length in minutes, the longest of its playback formats
+func (r Recording) Length() uint64 {
+	length := uint64(0)
+	for _, p := range r.Playback {
+		if p.Length > length {
+			length = p.Length
+		}
+	}
+	return length
+}
+
 // Meetings is BigBlueButton XML meetings section
 type Meetings struct {
 	XMLName xml.Name  `xml:"meetings"`
@@ -70,18 +113,93 @@ type Metadata struct {
 
 // Meeting is a meeting response containing information like name, id, created time, created date, ...
 type Meeting struct {
-	XMLName               xml.Name `xml:"meeting"`
-	ParticipantCount      uint64   `xml:"participantCount"`
-	ListenerCount         uint64   `xml:"listenerCount"`
-	VoiceParticipantCount uint64   `xml:"voiceParticipantCount"`
-	VideoCount            uint64   `xml:"videoCount"`
-	Recording             bool     `xml:"recording"`
+	XMLName                 xml.Name   `xml:"meeting"`
+	MeetingID               string     `xml:"meetingID"`
+	MeetingName             string     `xml:"meetingName"`
+	InternalMeetingID       string     `xml:"internalMeetingID"`
+	ParticipantCount        uint64     `xml:"participantCount"`
+	ListenerCount           uint64     `xml:"listenerCount"`
+	VoiceParticipantCount   uint64     `xml:"voiceParticipantCount"`
+	VideoCount              uint64     `xml:"videoCount"`
+	ModeratorCount          uint64     `xml:"moderatorCount"`
+	VoiceBridge             uint64     `xml:"voiceBridge"`
+	Duration                uint64     `xml:"duration"`
+	CreateTime              uint64     `xml:"createTime"`
+	MaxUsers                uint64     `xml:"maxUsers"`
+	HasUserJoined           bool       `xml:"hasUserJoined"`
+	IsBreakout              bool       `xml:"isBreakout"`
+	ParentMeetingID         string     `xml:"breakout>parentMeetingID"`
+	GuestPolicy             string     `xml:"guestPolicy"`
+	AutoStartRecording      *bool      `xml:"autoStartRecording"`
+	AllowStartStopRecording *bool      `xml:"allowStartStopRecording"`
+	WebcamsOnlyForModerator *bool      `xml:"webcamsOnlyForModerator"`
+	MuteOnStart             *bool      `xml:"muteOnStart"`
+	Attendees               []Attendee `xml:"attendees>attendee"`
+	Recording               bool       `xml:"recording"`
+	// WaitingGuests are only listed by getMeetingInfo on BigBlueButton 2.6 and later
+	WaitingGuests []WaitingGuest `xml:"guestsWaitingForApproval>guest"`
 	MetadataStruct
 }
 
+// WaitingGuest is a guest waiting in the meeting lobby for a moderator approval
+type WaitingGuest struct {
+	XMLName xml.Name `xml:"guest"`
+	UserID  string   `xml:"userID"`
+}
+
+// Moderators returns the meeting moderator count, counted from attendees roles when moderatorCount is not reported
+func (m Meeting) Moderators() uint64 {
+	if m.ModeratorCount > 0 {
+		return m.ModeratorCount
+	}
+
+	moderators := uint64(0)
+	for _, a := range m.Attendees {
+		if a.Role == "MODERATOR" {
+			moderators++
+		}
+	}
+	return moderators
+}
+
+// Attendee is a meeting attendee containing information like role, client type, ...
+type Attendee struct {
+	XMLName         xml.Name `xml:"attendee"`
+	UserID          string   `xml:"userID"`
+	ExternalUserID  string   `xml:"externalUserID"`
+	Role            string   `xml:"role"`
+	IsListeningOnly bool     `xml:"isListeningOnly"`
+	HasJoinedVoice  bool     `xml:"hasJoinedVoice"`
+	HasVideo        bool     `xml:"hasVideo"`
+	ClientType      string   `xml:"clientType"`
+	// Guest is only reported by servers flagging guest attendees
+	Guest bool `xml:"guest"`
+}
+
+// Identifier returns the attendee external user id, or the internal user id when missing
+func (a Attendee) Identifier() string {
+	if a.ExternalUserID != "" {
+		return a.ExternalUserID
+	}
+	return a.UserID
+}
+
 // HealthCheck is a api health check response
 type HealthCheck struct {
 	XMLName    xml.Name `xml:"response"`
 	ReturnCode string   `xml:"returncode"`
 	Version    string   `xml:"version"`
+	BBBVersion string   `xml:"bbbVersion"`
+	// ClockSkew is the server clock offset from local time, estimated from the HTTP Date header
+	ClockSkew    time.Duration `xml:"-"`
+	HasClockSkew bool          `xml:"-"`
+}
+
+// ServerVersion returns the BigBlueButton release from bbbVersion, returned by BigBlueButton 2.5 and later, or
+// the api version otherwise
+func (h *HealthCheck) ServerVersion() string {
+	if h.BBBVersion != "" {
+		return h.BBBVersion
+	}
+	return h.Version
 }
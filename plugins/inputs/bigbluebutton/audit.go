@@ -0,0 +1,173 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// audit is the report of a configuration audit
+type audit struct {
+	w        io.Writer
+	failures int
+}
+
+func (a *audit) ok(format string, args ...interface{}) {
+	fmt.Fprintf(a.w, "[OK]   %s\n", fmt.Sprintf(format, args...))
+}
+
+func (a *audit) warn(format string, args ...interface{}) {
+	fmt.Fprintf(a.w, "[WARN] %s\n", fmt.Sprintf(format, args...))
+}
+
+func (a *audit) fail(format string, args ...interface{}) {
+	a.failures++
+	fmt.Fprintf(a.w, "[FAIL] %s\n", fmt.Sprintf(format, args...))
+}
+
+// Audit validates the configuration against the live servers without emitting metrics: server reachability,
+// secret validity, api version, endpoints needed by the enabled options and expected metadata keys.
+// The report is written to w and an error is returned when a check failed
+func (b *BigBlueButton) Audit(w io.Writer) error {
+	a := &audit{w: w}
+	switch {
+	case b.BigBlueSwarmURL != "":
+		a.warn("BigBlueSwarm instances are discovered at gather time and are not audited")
+	case len(b.servers.load()) > 0:
+		for _, s := range b.servers.load() {
+			fmt.Fprintf(w, "server %s\n", s.tags["server"])
+			s.plugin.audit(a)
+		}
+	default:
+		fmt.Fprintf(w, "server %s\n", b.URL)
+		b.audit(a)
+	}
+
+	if a.failures > 0 {
+		return fmt.Errorf("audit failed with %d error(s)", a.failures)
+	}
+	return nil
+}
+
+func (b *BigBlueButton) audit(a *audit) {
+	h, err := b.getHealCheck()
+	if err != nil {
+		a.fail("server unreachable: %s", err)
+		return
+	}
+	a.ok("server reachable, BigBlueButton version %s", h.ServerVersion())
+
+	if b.ExpectedVersion != "" {
+		if versionMatches(h.ServerVersion(), b.ExpectedVersion) {
+			a.ok("version matches expected_version")
+		} else {
+			a.fail("version %s does not match expected_version %s", h.ServerVersion(), b.ExpectedVersion)
+		}
+	}
+
+	if err := b.auditEndpoint("getMeetings", b.endpoints.Load().getMeetings); err != nil {
+		a.fail("getMeetings: %s", err)
+		return
+	}
+	a.ok("secret valid, getMeetings answered")
+
+	if err := b.auditEndpoint("getRecordings", b.endpoints.Load().getRecordings); err != nil {
+		a.fail("getRecordings: %s", err)
+	} else {
+		a.ok("getRecordings answered")
+	}
+
+	if b.EnrichMeetingInfo {
+		// an unknown meeting answers notFound when the endpoint is available
+		err := b.auditEndpoint("getMeetingInfo", b.signedURL("getMeetingInfo", url.Values{"meetingID": {b.probeMeetingID}}), "notFound")
+		if err != nil {
+			a.fail("getMeetingInfo: %s", err)
+		} else {
+			a.ok("getMeetingInfo answered")
+		}
+	}
+
+	if b.ScaleliteMode {
+		if err := b.auditEndpoint("getServers", b.getScaleliteServersURL()); err != nil {
+			a.fail("getServers: %s", err)
+		} else {
+			a.ok("Scalelite getServers answered")
+		}
+	}
+
+	b.auditMetadataKeys(a)
+}
+
+// startupCheck checks the server answers the health check and accepts the secret key, so Init fails fast with
+// a descriptive error instead of gathering empty metrics
+func (b *BigBlueButton) startupCheck() error {
+	e := b.endpoints.Load()
+	if err := b.auditEndpoint("health check", e.healthCheck); err != nil {
+		return fmt.Errorf("startup check: health check %s failed, check url and path_prefix: %s", e.healthCheck, err)
+	}
+
+	_, err := b.getMeetings()
+	if isAuthError(err) && b.rotateSecretKey() {
+		return nil
+	}
+	if isAuthError(err) {
+		return fmt.Errorf("startup check: getMeetings checksum rejected, check secret_key and checksum_algorithm")
+	}
+	if err != nil {
+		return fmt.Errorf("startup check: getMeetings failed: %s", err)
+	}
+
+	return nil
+}
+
+// auditEndpoint calls the api and check it answered SUCCESS, or one of the accepted message keys
+func (b *BigBlueButton) auditEndpoint(apiCallName string, u string, acceptedMessageKeys ...string) error {
+	body, _, err := b.api(u)
+	if err != nil {
+		return err
+	}
+
+	var response APIResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return err
+	}
+
+	if response.ReturnCode == "SUCCESS" || contains(acceptedMessageKeys, response.MessageKey) {
+		return nil
+	}
+	if response.MessageKey == "checksumError" {
+		return fmt.Errorf("checksum rejected, check secret_key and checksum_algorithm")
+	}
+	return fmt.Errorf("returncode %s, messageKey %s", response.ReturnCode, response.MessageKey)
+}
+
+// auditMetadataKeys check that the gather_by_metadata keys are found on current meetings or recordings
+func (b *BigBlueButton) auditMetadataKeys(a *audit) {
+	keys := b.metadataKeys()
+	if len(keys) == 0 {
+		return
+	}
+
+	m, err := b.getMeetings()
+	if err != nil {
+		a.fail("getMeetings: %s", err)
+		return
+	}
+	r, err := b.getRecordings(context.Background())
+	if err != nil {
+		a.fail("getRecordings: %s", err)
+		return
+	}
+
+	groups := b.groupByMetadata(keys, m, r, &HealthCheck{})
+	for _, k := range keys {
+		if len(groups[k]) > 0 {
+			a.ok("metadata %s found with %d value(s)", k, len(groups[k]))
+		} else {
+			a.warn("metadata %s not found on current meetings and recordings", k)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// dailyRecordings is the recordings history of a single day
+type dailyRecordings struct {
+	recordings          uint64
+	publishedRecordings uint64
+	size                uint64
+}
+
+// backfillPageSize is the number of recordings requested per getRecordings page while walking the library
+const backfillPageSize = 100
+
+// getBackfillURL returns the getRecordings url of a page of the whole library, recordings of every state included.
+// Servers older than BigBlueButton 2.6 ignore offset and limit and return the whole library at once
+func (b *BigBlueButton) getBackfillURL(offset int) string {
+	params := url.Values{}
+	for k, v := range b.ExtraParams["getRecordings"] {
+		params.Set(k, v)
+	}
+	params.Set("state", "any")
+	params.Set("offset", strconv.Itoa(offset))
+	params.Set("limit", strconv.Itoa(backfillPageSize))
+
+	return b.signedURL("getRecordings", params)
+}
+
+// gatherBackfill walks the whole recordings library page by page and emits one bigbluebutton_recordings_daily
+// point per day, timestamped at the day start. It runs once per plugin lifetime, and again on the next gather
+// when a page can't be fetched
+func (b *BigBlueButton) gatherBackfill(acc telegraf.Accumulator) error {
+	if b.backfillDone {
+		return nil
+	}
+
+	days := make(map[time.Time]*dailyRecordings)
+	firstID := ""
+	for offset := 0; ; offset += backfillPageSize {
+		response, err := b.getRecordingsFrom(context.Background(), b.getBackfillURL(offset), true)
+		if err != nil {
+			return err
+		}
+
+		rs := response.Recordings.Values
+		// a page starting like the previous one means offset is not supported, the whole library was returned
+		if len(rs) == 0 || (offset > 0 && rs[0].RecordID == firstID) {
+			break
+		}
+		firstID = rs[0].RecordID
+		addDailyRecordings(days, rs)
+		if len(rs) != backfillPageSize {
+			break
+		}
+	}
+	b.backfillDone = true
+
+	for day, d := range days {
+		fields := map[string]interface{}{
+			"recordings":           d.recordings,
+			"published_recordings": d.publishedRecordings,
+			"size_bytes":           d.size,
+		}
+		acc.AddFields("bigbluebutton_recordings_daily", fields, map[string]string{}, day)
+	}
+
+	return nil
+}
+
+// addDailyRecordings adds the recordings to the history of the day they started
+func addDailyRecordings(days map[time.Time]*dailyRecordings, rs []Recording) {
+	for _, r := range rs {
+		if r.StartTime == 0 {
+			continue
+		}
+
+		start := time.Unix(0, int64(r.StartTime)*int64(time.Millisecond)).UTC()
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		d, ok := days[day]
+		if !ok {
+			d = &dailyRecordings{}
+			days[day] = d
+		}
+
+		d.recordings++
+		d.publishedRecordings += boolToUint64(r.Published)
+		d.size += r.Size
+	}
+}
@@ -2,37 +2,245 @@
 package bigbluebutton
 
 import (
+	"context"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/plugins/common/proxy"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
+	"golang.org/x/sync/errgroup"
 )
 
 // BigBlueButton is the global configuration object
 type BigBlueButton struct {
-	URL              string   `toml:"url"`
-	PathPrefix       string   `toml:"path_prefix"`
-	SecretKey        string   `toml:"secret_key"`
-	Username         string   `toml:"username"`
-	Password         string   `toml:"password"`
-	GatherByMetadata []string `toml:"gather_by_metadata"`
-	getMeetingsURL   string
-	getRecordingsURL string
-	healthCheckURL   string
+	URL                            string                       `toml:"url"`
+	PathPrefix                     string                       `toml:"path_prefix"`
+	PathPrefixes                   []string                     `toml:"path_prefixes"`
+	SecretKey                      string                       `toml:"secret_key"`
+	Username                       string                       `toml:"username"`
+	Password                       string                       `toml:"password"`
+	GatherByMetadata               []string                     `toml:"gather_by_metadata"`
+	RequireHTTPS                   bool                         `toml:"require_https"`
+	ExpectedVersion                string                       `toml:"expected_version"`
+	RecordingsByState              bool                         `toml:"recordings_by_state_measurement"`
+	VoiceBridgeRanges              []VoiceBridgeRange           `toml:"voice_bridge_ranges"`
+	GatherClientTypes              bool                         `toml:"gather_client_types"`
+	RecordingFailureWindow         config.Duration              `toml:"recording_failure_window"`
+	PerRecordingMetrics            bool                         `toml:"per_recording_metrics"`
+	RecordingMetadataTags          []string                     `toml:"recording_metadata_tags"`
+	GatherUniqueUsers              bool                         `toml:"gather_unique_users"`
+	GatherHourlyProfile            bool                         `toml:"gather_hourly_profile"`
+	ExtraParams                    map[string]map[string]string `toml:"extra_params"`
+	PlaybackURLTemplate            string                       `toml:"playback_url_template"`
+	GroupByOriginServer            bool                         `toml:"group_by_origin_server"`
+	CollectorTimeBudget            config.Duration              `toml:"collector_time_budget"`
+	OfflineBackoff                 config.Duration              `toml:"offline_backoff"`
+	OfflineBackoffMax              config.Duration              `toml:"offline_backoff_max"`
+	Probe                          bool                         `toml:"probe"`
+	GatherByMetadataFile           string                       `toml:"gather_by_metadata_file"`
+	GatherByMetadataFileRefresh    config.Duration              `toml:"gather_by_metadata_file_refresh"`
+	EnrichMeetingInfo              bool                         `toml:"enrich_meeting_info"`
+	MeetingInfoLimit               int                          `toml:"meeting_info_limit"`
+	MeetingInfoCacheTTL            config.Duration              `toml:"meeting_info_cache_ttl"`
+	MaxConcurrentRequests          int                          `toml:"max_concurrent_requests"`
+	Servers                        []Server                     `toml:"servers"`
+	BalancerURL                    string                       `toml:"balancer_url"`
+	BalancerSecretKey              string                       `toml:"balancer_secret_key"`
+	ScaleliteMode                  bool                         `toml:"scalelite_mode"`
+	BigBlueSwarmURL                string                       `toml:"bigblueswarm_url"`
+	BigBlueSwarmAPIKey             string                       `toml:"bigblueswarm_api_key"`
+	SSHTunnelHost                  string                       `toml:"ssh_tunnel_host"`
+	SSHTunnelUser                  string                       `toml:"ssh_tunnel_user"`
+	SSHTunnelKey                   string                       `toml:"ssh_tunnel_key"`
+	SSHTunnelKnownHosts            string                       `toml:"ssh_tunnel_known_hosts"`
+	SSHTunnelInsecureIgnoreHostKey bool                         `toml:"ssh_tunnel_insecure_ignore_host_key"`
+	Backfill                       bool                         `toml:"backfill"`
+	WebhooksListen                 string                       `toml:"webhooks_listen"`
+	WebhooksPath                   string                       `toml:"webhooks_path"`
+	WebhooksToken                  string                       `toml:"webhooks_token"`
+	PerMeetingMetrics              bool                         `toml:"per_meeting_metrics"`
+	MeetingMetadataFields          []string                     `toml:"meeting_metadata_fields"`
+	PerMeetingNameTag              bool                         `toml:"per_meeting_name_tag"`
+	GatherMeetingPolicies          bool                         `toml:"gather_meeting_policies"`
+	MetadataWorkers                int                          `toml:"metadata_workers"`
+	GatherRecordingStates          bool                         `toml:"gather_recording_states"`
+	ChecksumAlgorithm              string                       `toml:"checksum_algorithm"`
+	RecordingsByAge                bool                         `toml:"recordings_by_age"`
+	GatherAudioStates              bool                         `toml:"gather_audio_states"`
+	EmitEvents                     bool                         `toml:"emit_events"`
+	Timeout                        config.Duration              `toml:"timeout"`
+	ConnectTimeout                 config.Duration              `toml:"connect_timeout"`
+	ReadTimeout                    config.Duration              `toml:"read_timeout"`
+	MeetingIDMetadata              map[string]string            `toml:"meeting_id_metadata"`
+	ReturnCodeTag                  bool                         `toml:"returncode_tag"`
+	ResolvedIPTag                  bool                         `toml:"resolved_ip_tag"`
+	RecordingsCacheInterval        config.Duration              `toml:"recordings_cache_interval"`
+	Precision                      config.Duration              `toml:"precision"`
+	GatherResponseTimes            bool                         `toml:"gather_response_times"`
+	GatherGuestPolicies            bool                         `toml:"gather_guest_policies"`
+	GatherVideoPublishers          bool                         `toml:"gather_video_publishers"`
+	Fields                         []string                     `toml:"fields"`
+	MetadataMeasurementTemplate    string                       `toml:"metadata_measurement_template"`
+	EmitCounters                   bool                         `toml:"emit_counters"`
+	GatherByMetadataCombined       bool                         `toml:"gather_by_metadata_combined"`
+	MetadataInclude                []string                     `toml:"metadata_include"`
+	MetadataExclude                []string                     `toml:"metadata_exclude"`
+	RecordingsStates               []string                     `toml:"recordings_states"`
+	MetadataValueRegex             []MetadataValueRegex         `toml:"metadata_value_regex"`
+	WebManagementURL               string                       `toml:"web_management_url"`
+	TenantMapFile                  string                       `toml:"tenant_map_file"`
+	TenantMapFileReload            bool                         `toml:"tenant_map_file_reload"`
+	MetadataUnknownValue           string                       `toml:"metadata_unknown_value"`
+	SchemaVersionTag               bool                         `toml:"schema_version_tag"`
+	MetadataKeyStripPrefix         string                       `toml:"metadata_key_strip_prefix"`
+	MetadataKeyLowercase           bool                         `toml:"metadata_key_lowercase"`
+	MetadataKeySanitize            bool                         `toml:"metadata_key_sanitize"`
+	TCPCheck                       bool                         `toml:"tcp_check"`
+	BandwidthAudioKbps             float64                      `toml:"bandwidth_audio_kbps"`
+	BandwidthVideoKbps             float64                      `toml:"bandwidth_video_kbps"`
+	KnownMetadataValues            map[string][]string          `toml:"known_metadata_values"`
+	GatherGuests                   bool                         `toml:"gather_guests"`
+	SkipIdle                       bool                         `toml:"skip_idle"`
+	GatherMeetingAges              bool                         `toml:"gather_meeting_ages"`
+	GatherSeatUtilization          bool                         `toml:"gather_seat_utilization"`
+	GatherDistinctOrigins          bool                         `toml:"gather_distinct_origins"`
+	GatherAttendeeRoles            bool                         `toml:"gather_attendee_roles"`
+	GatherPlaybackFormats          bool                         `toml:"gather_playback_formats"`
+	DebugMemoryStats               bool                         `toml:"debug_memory_stats"`
+	GatherRecordingTotals          bool                         `toml:"gather_recording_totals"`
+	GatherPendingRecordingAge      bool                         `toml:"gather_pending_recording_age"`
+	GatherEmptyMeetings            bool                         `toml:"gather_empty_meetings"`
+	GroupBreakoutsWithParent       bool                         `toml:"group_breakouts_with_parent"`
+	SecretKeyFile                  string                       `toml:"secret_key_file"`
+	SecretKeys                     []string                     `toml:"secret_keys"`
+	StartupCheck                   bool                         `toml:"startup_check"`
+	HostHeader                     string                       `toml:"host_header"`
+	serverURL                      *url.URL
+	endpoints                      *atomic.Pointer[endpoints]
+
+	Log telegraf.Logger `toml:"-"`
 
 	tls.ClientConfig
 	proxy.HTTPProxy
-	client *http.Client
+	client         *atomic.Pointer[http.Client]
+	lastVersion    string
+	prefixResolved bool
+	// hashAlgorithm is the checksum algorithm in use, negotiated with the server when checksum_algorithm is auto.
+	// checksumAuto is set when checksum_algorithm is auto, checksumNegotiated once the server accepted an algorithm.
+	// secret is the secret key in use, the one accepted by the server when secret_keys are set
+	hashAlgorithm      string
+	checksumAuto       bool
+	checksumNegotiated bool
+	secret             string
+	recordings         *recordingTracker
+	uniqueUsers        *uniqueUsers
+	hourlyProfile      *hourlyProfile
+	lastRecordings     *RecordingsResponse
+	// recordingsFetchedAt is the time lastRecordings were fetched
+	recordingsFetchedAt time.Time
+	events              eventTracker
+	counters            counterTracker
+	rateLimit           *rateLimitState
+	stats               *selfStats
+	backfillDone        bool
+	backoff             time.Duration
+	nextAttempt         time.Time
+
+	fileMetadataKeys   []string
+	metadataFileReadAt time.Time
+	meetingInfoCache   map[string]cachedMeetingInfo
+
+	webhooks *webhooks
+
+	metadataFilter    filter.Filter
+	meetingIDPatterns map[string]*regexp.Regexp
+	metadataRegexes   []*regexp.Regexp
+	// probeMeetingID is the meeting identifier of the synthetic probe, kept when Init is called again
+	probeMeetingID string
+	// knownMetadataValues is known_metadata_values keyed by normalized metadata key
+	knownMetadataValues map[string][]string
+	tenantMap           map[string]map[string]string
+	tenantMapModTime    time.Time
+
+	servers         *serverSet
+	balancer        *BigBlueButton
+	bigBlueSwarmURL *url.URL
+}
+
+// MetadataValueRegex rewrites the values of a metadata key, replacing matches of the pattern.
+// The replacement can reference capture groups, as in regexp.ReplaceAllString
+type MetadataValueRegex struct {
+	Key         string `toml:"key"`
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+}
+
+// VoiceBridgeRange maps an inclusive voice bridge number range to a label
+type VoiceBridgeRange struct {
+	Label string `toml:"label"`
+	From  uint64 `toml:"from"`
+	To    uint64 `toml:"to"`
+}
+
+// Contains check if the voice bridge number is in the range
+func (v VoiceBridgeRange) Contains(voiceBridge uint64) bool {
+	return voiceBridge >= v.From && voiceBridge <= v.To
 }
 
 var defaultPathPrefix = "/bigbluebutton"
 
+// originServerMetadata is the metadata set by BigBlueButton frontends and kept by Scalelite to identify the meeting origin
+var originServerMetadata = "bbb-origin-server-name"
+
+var defaultRecordingFailureWindow = config.Duration(time.Hour)
+
+var defaultMetadataWorkers = 4
+
+var defaultMaxConcurrentRequests = 4
+
+var defaultTimeout = config.Duration(5 * time.Second)
+
+// schemaVersion identifies the measurements, fields and tags layout emitted by the plugin. It is bumped when
+// existing fields or tags are renamed, removed or change meaning, not when new ones are added
+const schemaVersion = "1"
+
+// resolveTimeout bounds the server host name resolution of resolved_ip_tag
+var resolveTimeout = 2 * time.Second
+
+// checksumHashes maps the supported checksum_algorithm values to their hash function
+var checksumHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// negotiatedChecksumAlgorithms lists the algorithms tried, in order, when checksum_algorithm is auto
+var negotiatedChecksumAlgorithms = []string{"sha1", "sha256", "sha512"}
+
+// maxRetryAfter is the longest Retry-After delay honored within a gather. Longer delays fail the request
+var maxRetryAfter = 10 * time.Second
+
 var sampleConfig = `
 	## Required BigBlueButton server url
 	url = "http://localhost:8090"
@@ -40,263 +248,2247 @@ var sampleConfig = `
 	## BigBlueButton path prefix. Default is "/bigbluebutton"
 	# path_prefix = "/bigbluebutton"
 
-	## Required BigBlueButton secret key
+	## BigBlueButton path prefixes tried in order at startup. The first one answering is used. Overrides path_prefix
+	# path_prefixes = ["/bigbluebutton", "/tenant/bigbluebutton"]
+
+	## Required BigBlueButton secret key. secret_key, secret_keys, the servers and balancer secret keys, username and
+	## password also accept "@env:NAME", read from the environment variable NAME, and "@file:PATH", read from the
+	## file at PATH, so that secrets stay out of the configuration file. Secrets are never logged
 	secret_key = ""
 
+	## Read the secret key from a file instead, e.g. a mounted Kubernetes secret. Environment variables in the path
+	## are expanded. The file is read again after a failed gather, so a rotated secret is picked up
+	# secret_key_file = "/run/secrets/bbb_secret"
+
+	## Additional secret keys tried in order when the server rejects the checksum, e.g. the new secret during a
+	## secret rotation. The accepted secret key is kept until it is rejected in turn
+	# secret_keys = []
+
+	## Check on startup that the server answers the health check and accepts the secret key. Telegraf fails to start
+	## with a descriptive error on a wrong url, path prefix or secret instead of gathering empty metrics
+	# startup_check = false
+
+	## Checksum hash algorithm (sha1, sha256 or sha512). Must be allowed by the server supportedChecksumAlgorithms
+	## setting. BigBlueButton 2.6+ servers can be configured to reject sha1 checksums. With auto, sha1, sha256 then
+	## sha512 are tried and the first one accepted is used and reported in the checksum_algorithm tag
+	# checksum_algorithm = "sha1"
+
 	## Gather metrics by metadata
 	# Using this option, gathering data will also insert metrics grouped by metadata configuration
 	# gather_by_metadata = []
 
+	## Optional file containing additional metadata keys to gather by, one per line. The file is re-read every
+	## gather_by_metadata_file_refresh so new keys are picked up without reloading Telegraf
+	# gather_by_metadata_file = "/etc/telegraf/bigbluebutton_metadata"
+	# gather_by_metadata_file_refresh = "1m"
+
+	## Measurement name of gather_by_metadata groups, {{key}} is replaced by the metadata key. Defaults to the bare key,
+	## set a prefix such as "bigbluebutton_{{key}}" to namespace them for Prometheus or Graphite outputs
+	# metadata_measurement_template = "{{key}}"
+
+	## Group by the combination of all gather_by_metadata keys instead of each key independently. One series is emitted
+	## per unique combination, with each key as its own tag, in a measurement named after the keys joined by underscores
+	## (e.g. tenant_course). Meetings and recordings missing one of the keys are ignored, see metadata_unknown_value
+	# gather_by_metadata_combined = false
+
+	## Group meetings and recordings lacking a gather_by_metadata key under this value instead of ignoring them, so
+	## that per metadata records add up to the global record
+	# metadata_unknown_value = "unknown"
+
+	## Metadata keys normalization, applied to meetings and recordings metadata and to the keys of every metadata
+	## option, bbb-origin-server-name lookups included. Frontends writing Tenant, tenant and bbb-tenant are then grouped
+	## under the same tenant key. Fields and tags of meeting_metadata_fields and recording_metadata_tags use the
+	## normalized keys
+	# metadata_key_strip_prefix = "bbb-"
+	# metadata_key_lowercase = false
+	## Replace characters other than letters, digits, underscores and dashes by underscores
+	# metadata_key_sanitize = false
+
+	## Glob filters on metadata values. Only gather_by_metadata groups whose value matches metadata_include, and does not
+	## match metadata_exclude, produce series. Protects against integrations writing high cardinality values
+	# metadata_include = ["acme", "example-*"]
+	# metadata_exclude = ["test-*"]
+
+	## Optional file mapping raw metadata values to friendly names used in gather_by_metadata tags, per metadata key.
+	## metadata_include and metadata_exclude match the raw values, names are applied to the values kept.
+	## JSON when the file has a .json extension, TOML otherwise:
+	##   [tenant]
+	##   "5f1e0c2a" = "Acme"
+	## Loaded at startup, and re-read when modified if tenant_map_file_reload is enabled
+	# tenant_map_file = "/etc/telegraf/bigbluebutton_tenants.toml"
+	# tenant_map_file_reload = false
+
+	## Number of concurrent workers computing gather_by_metadata groups. Reduces gather latency on hosts with
+	## hundreds of metadata values
+	# metadata_workers = 4
+
+	## Optional BigBlueSwarm load balancer admin api. When set, url and servers are ignored: instances are
+	## discovered from the balancer and gathered with their own secret, tagged with server. Tenants are
+	## reported in the bigbluebutton_bigblueswarm_tenant measurement
+	# bigblueswarm_url = "https://bigblueswarm.example.com"
+	# bigblueswarm_api_key = ""
+
 	## Optional HTTP Basic Auth Credentials
 	# username = "username"
 	# password = "pa$$word
 
+	## HTTP request timeout, including connection and response body read, so that a hung server does not stall the gather.
+	## connect_timeout bounds the connection establishment and read_timeout the wait for response headers, 0 means no limit
+	# timeout = "5s"
+	# connect_timeout = "0s"
+	# read_timeout = "0s"
+
+	## Check the server TCP reachability, distinct from the API health, and report tcp_reachable and api_online fields
+	## to separate network issues from application failures. The server url host is dialed directly, bypassing proxies
+	# tcp_check = false
+
+	## Add a returncode tag to every point: SUCCESS when getMeetings, getRecordings and the health check all succeeded,
+	## FAILED otherwise. Identifies points of partially failed gathers
+	# returncode_tag = false
+
+	## Tag every point with the address the server host name resolves to (resolved_ip tag), to make DNS cutovers
+	## visible. The name is resolved locally, through neither http_proxy_url nor the SSH tunnel
+	# resolved_ip_tag = false
+
+	## Tag every point with schema_version, the plugin fields and tags layout version. It is bumped when existing fields
+	## or tags are renamed, removed or change meaning, so downstream tasks can branch on it
+	# schema_version_tag = false
+
+	## Omit points while the server is online without any running meeting, events are still emitted. Minimizes write
+	## volume for fleets of mostly idle servers. Offline servers are still reported
+	# skip_idle = false
+
+	## Truncate emitted points timestamps to the given precision. Improves compression in some TSDBs
+	# precision = "1s"
+
+	## Debug: emit a bigbluebutton_debug point with the bytes and objects allocated during each gather, and the heap in
+	## use, to measure the cost of options like recordings or per meeting metrics. Reading memory statistics briefly
+	## stops the agent, keep it disabled in production
+	# debug_memory_stats = false
+
+	## Refuse to send checksum signed requests over plain HTTP, except to localhost
+	# require_https = false
+
+	## Expected BigBlueButton version. When set, version_mismatch field reports whether the server runs another version.
+	## Matched on dotted components, so 2.7 matches 2.7.3. Servers older than 2.5 only report the api version 2.0
+	# expected_version = "2.7"
+
+	## Report getMeetings, getRecordings and health check response times, to see the api degrading before it fails
+	# gather_response_times = false
+
+	## Report participants per client type (html5, dial_in, ...) as <client type>_participants fields
+	# gather_client_types = false
+
+	## Report viewer_participants and moderator_participants, the attendees per role, on the bigbluebutton and per
+	## metadata measurements. Computed from meeting attendees, use enrich_meeting_info when getMeetings does not list attendees
+	# gather_attendee_roles = false
+
+	## Report attendees per audio state: connected to voice, listen only, and pending (in echo test or without audio)
+	# gather_audio_states = false
+
+	## Split video participants into webcam publishers and viewers (attendees of meetings with at least one webcam).
+	## Computed from meeting attendees, use enrich_meeting_info when getMeetings does not list attendees
+	# gather_video_publishers = false
+
+	## Report guest_participants, attendees flagged as guest when the server reports the flag. Use enrich_meeting_info
+	## when getMeetings does not list attendees. With enrich_meeting_info, waiting_guests also counts the guests waiting
+	## in the lobby of the enriched meetings, listed by getMeetingInfo on BigBlueButton 2.6 and later
+	# gather_guests = false
+
+	## Per stream bitrates used to estimate the server bandwidth: participants x audio kbps + webcams x video kbps.
+	## When set, an estimated_bandwidth_mbps field is reported, also on per meeting points, as a provisioning signal
+	# bandwidth_audio_kbps = 0.0
+	# bandwidth_video_kbps = 0.0
+
+	## Emit monotonically increasing totals, participant minutes and meeting starts, for backends preferring rates over
+	## counters to gauges. Totals are kept in memory and restart from zero when Telegraf restarts
+	# emit_counters = false
+
+	## Allowlist of the bigbluebutton measurement fields to emit. When set, getRecordings and the health check are skipped
+	## if none of the allowed fields depends on them, collectors relying on recordings are then skipped and the version tag
+	## is not reported
+	# fields = ["meetings", "participants", "online"]
+
+	## Report meetings policies: meetings with a duration cap, and meetings with autoStartRecording,
+	## allowStartStopRecording, webcamsOnlyForModerator and muteOnStart enabled when the server reports these settings
+	# gather_meeting_policies = false
+
+	## Report longest_meeting_duration_seconds and total_meeting_age_seconds, computed from meetings createTime, to detect
+	## zombie meetings never ended and holding FreeSWITCH resources
+	# gather_meeting_ages = false
+
+	## Report seats_configured, the sum of meetings maxUsers, and seat_utilization_percent, the participants of these
+	## meetings against their seats. Meetings without maxUsers limit are ignored
+	# gather_seat_utilization = false
+
+	## Report distinct_origins, the number of distinct bbb-origin-server-name metadata values among running meetings
+	# gather_distinct_origins = false
+
+	## Report empty_meetings, meetings without participants, and never_joined_meetings, the empty meetings nobody
+	## ever joined (hasUserJoined false), like rooms created by API integrations and never used
+	# gather_empty_meetings = false
+
+	## Emit meetings counts per guest policy (ALWAYS_ACCEPT, ASK_MODERATOR, ALWAYS_DENY) in the bigbluebutton_guest_policy
+	## measurement, to audit open door rooms. Meetings are counted as unknown when the server does not report their policy
+	# gather_guest_policies = false
+
+	## Delay after which a recording that disappeared while processing without being published is counted as failed.
+	## recordings_published_delta and recordings_failed need processing recordings, listed when gather_recording_states
+	## is enabled or recordings_states includes processing or processed
+	# recording_failure_window = "1h"
+
+	## Fetch getRecordings at most once per interval and reuse the previous recordings in between, while meetings and
+	## health are gathered every interval. Recordings change slowly and getRecordings is the heaviest call. 0 fetches on every gather
+	# recordings_cache_interval = "0s"
+
+	## Request recordings in any state (getRecordings state=any) and report processing_recordings, processed_recordings,
+	## unpublished_recordings and deleted_recordings fields to monitor the recording pipeline
+	# gather_recording_states = false
+
+	## Report the recordings per playback format (presentation, video, podcast, screenshare, notes) as
+	## <format>_format_recordings fields, to check post-processing workers keep up with each format
+	# gather_playback_formats = false
+
+	## Report recordings_total_bytes and recordings_total_minutes, the recordings size and length sums, for storage
+	## forecasting. Size is reported by BigBlueButton 2.3+
+	# gather_recording_totals = false
+
+	## Report oldest_pending_recording_age_seconds, the age of the oldest recording processing or processed but not
+	## published, to alert on a stuck recording pipeline. Pending recordings are only listed by getRecordings when
+	## gather_recording_states is enabled, or recordings_states includes processing and processed
+	# gather_pending_recording_age = false
+
+	## Recording states requested with one getRecordings call each, fetched concurrently and merged. Keeps gather
+	## latency flat when several states are needed, on servers where state=any is slow or unsupported
+	# recordings_states = ["published", "processing", "deleted"]
+
+	## Emit one point per meeting in the bigbluebutton_meeting measurement
+	# per_meeting_metrics = false
+
+	## Add the meeting name as name tag on per meeting points
+	# per_meeting_name_tag = false
+
+	## Count breakout room participants in their parent meeting (breakout parentMeetingID) on per meeting, per origin
+	## and per metadata points, so a class with breakout rooms is reported as one meeting
+	# group_breakouts_with_parent = false
+
+	## Meeting metadata keys added as string fields, not tags, on per meeting points. Suited to high cardinality
+	## values like a course url
+	# meeting_metadata_fields = ["bbb-context-url"]
+
+	## Emit one point per recording in the bigbluebutton_recording measurement
+	# per_recording_metrics = false
+
+	## Recording metadata keys added as tags on per recording points
+	# recording_metadata_tags = ["bbb-origin-server-name", "tenant"]
+
+	## Walk the whole recordings library once, on the first gather, and emit per day recording counts and sizes
+	## in the bigbluebutton_recordings_daily measurement, timestamped at the day start. Seeds dashboards with history.
+	## Recordings of every state are requested, by pages of 100 on BigBlueButton 2.6+
+	# backfill = false
+
+	## Estimate distinct users seen over the last hour and day (unique_users_1h and unique_users_24h fields)
+	# gather_unique_users = false
+
+	## Report the current hour meetings and participants averages along with the same hour last week averages.
+	## Keeps one week of hourly history in memory
+	# gather_hourly_profile = false
+
+	## Playback url of a recording. When set, the most recent published recording playback is probed with a HEAD request
+	## and reported in the playback_reachable field. {{record_id}} is replaced by the recording identifier
+	# playback_url_template = "https://bbb.example.com/playback/presentation/2.3/{{record_id}}"
+
+	## Group meetings and recordings by their bbb-origin-server-name metadata in the bigbluebutton_origin measurement.
+	## Useful when gathering through a Scalelite load balancer
+	# group_by_origin_server = false
+
+	## Gather time budget, usually the collection interval. Once 80% of the budget is used, optional collectors
+	## (recordings, meeting info, playback probe) are skipped or cut for the cycle and collectors_skipped field reports
+	## how many were skipped. Cut recordings are reported like failed ones, see recordings_cache_interval
+	# collector_time_budget = "10s"
+
+	## Poll an offline server less frequently. After a failed gather, next attempts are delayed by offline_backoff,
+	## doubled on each failure up to offline_backoff_max. Normal cadence resumes on recovery
+	# offline_backoff = "0s"
+	# offline_backoff_max = "10m"
+
+	## Emit a bigbluebutton_event point, tagged with event, on state transitions: server_offline, server_online and
+	## meetings_dropped_to_zero. For alerting pipelines consuming events rather than gauges
+	# emit_events = false
+
+	## Run a synthetic create, join and end scenario on each gather. Results are reported in the bigbluebutton_probe measurement.
+	## The probe meeting id is telegraf-probe suffixed with the host name and a random token, unique per plugin instance
+	# probe = false
+
+	## Optional bbb-webhooks listener. When set, bbb-webhooks callbacks (meeting, user and rap-* events) received on
+	## webhooks_path are counted and reported in the bigbluebutton_webhooks measurement. Catches short meetings and
+	## join spikes missed between two polls. Register http://<telegraf host><webhooks_listen><webhooks_path>?token=<webhooks_token>
+	## as hook callback. webhooks_token is required, callbacks without it are rejected
+	# webhooks_listen = ":8095"
+	# webhooks_path = "/webhooks"
+	# webhooks_token = ""
+
+	## Call getMeetingInfo for running meetings to get detailed meeting data (attendees, guest and recording policies).
+	## Participant counts stay those of getMeetings. meeting_info_limit bounds the calls to the biggest meetings,
+	## 0 means no limit
+	# enrich_meeting_info = false
+	# meeting_info_limit = 20
+
+	## Cache getMeetingInfo results per meeting for the given duration, 0 disables the cache
+	# meeting_info_cache_ttl = "0s"
+
+	## Maximum number of getMeetingInfo calls in flight at once, so enriching many meetings doesn't hammer the server
+	# max_concurrent_requests = 4
+
+	## Scalelite mode. url is a Scalelite load balancer and secret_key its secret. In addition to the pool
+	## metrics, the Scalelite getServers api is called to emit one bigbluebutton_scalelite_server point per server
+	# scalelite_mode = false
+
+	## Optional bbb-web Spring Boot actuator base url. When set, JVM heap and threads and Tomcat request threads stats
+	## are gathered in the bigbluebutton_web measurement, to correlate API slowness with JVM pressure
+	# web_management_url = "http://127.0.0.1:8090/actuator"
+
+	## Emit recording lifecycle counters into a dedicated bigbluebutton_recordings measurement tagged by state
+	# recordings_by_state_measurement = false
+
+	## Emit recordings counts by age (this_month, 1_6_months, over_6_months), computed from their start time,
+	## in the bigbluebutton_recordings_by_age measurement. Helps retention policy monitoring and cleanup planning
+	# recordings_by_age = false
+
 	## Optional HTTP Proxy support
 	# http_proxy_url = ""
 
+	## Optional SSH tunnel. Requests are sent through the jump host, for servers only reachable from a management network.
+	## Connecting to the jump host is bounded by connect_timeout, or timeout when not set
+	# ssh_tunnel_host = "jump.example.com:22"
+	# ssh_tunnel_user = "telegraf"
+	# ssh_tunnel_key = "/etc/telegraf/id_ed25519"
+	# ssh_tunnel_known_hosts = "/etc/telegraf/known_hosts"
+	# ssh_tunnel_insecure_ignore_host_key = false
+
 	## Optional TLS Config
 	# tls_ca = "/etc/telegraf/ca.pem"
 	# tls_cert = "/etc/telegraf/cert.pem"
 	# tls_key = "/etc/telegraf/key.pem"
 
+	## Server name expected in the server certificate, when the url addresses the server by IP or another name
+	# tls_server_name = "bbb.example.com"
+
 	## Use TLS but skip chain & host verification
 	# insecure_skip_verify = false
+
+	## Host header sent with api requests, to reach a server by IP while routing on the virtual host name expected
+	## by its reverse proxy. Combine with tls_server_name for https urls
+	# host_header = "bbb.example.com"
+
+	## Optional extra query parameters per API call. Parameters are included in the request checksum
+	# [inputs.bigbluebutton.extra_params.getRecordings]
+	#   state = "any"
+
+	## Optional metadata derived from the external meeting id when missing, for LMS integrations encoding the tenant
+	## in the meeting id (e.g. acme-12345). The value is the pattern first capture group. Derived metadata are used
+	## by gather_by_metadata
+	# [inputs.bigbluebutton.meeting_id_metadata]
+	#   tenant = "^([a-z0-9]+)-"
+
+	## Optional known values per gather_by_metadata key. Their series are emitted with zero fields when no meeting
+	## or recording has the value, so that alerts can fire on usage dropping to zero. Not applied to combined keys
+	# [inputs.bigbluebutton.known_metadata_values]
+	#   tenant = ["acme", "globex"]
+
+	## Optional metadata values normalization, applied before grouping. Matches of pattern in the key values are
+	## replaced by replacement, which can reference capture groups. Avoids duplicate groups for messy values
+	# [[inputs.bigbluebutton.metadata_value_regex]]
+	#   key = "bbb-origin-server-name"
+	#   pattern = "^(?:https?://)?([^/:]+).*$"
+	#   replacement = "${1}"
+
+	## Optional voice bridge ranges. Meetings and voice participants are reported per range label
+	## in the bigbluebutton_voice_bridge measurement
+	# [[inputs.bigbluebutton.voice_bridge_ranges]]
+	#   label = "sip-trunk-a"
+	#   from = 70000
+	#   to = 79999
+
+	## Optional Scalelite load balancer of the servers below and its secret. When set, its getServers api is called
+	## on every gather and the servers points are tagged with their balancer_state (enabled, cordoned, disabled),
+	## matched on the server host. Without balancer_secret_key, secret_key is used
+	# balancer_url = "https://scalelite.example.com"
+	# balancer_secret_key = ""
+
+	## Optional list of servers gathered concurrently by this plugin instance. When set, url, path_prefix
+	## and path_prefixes above are ignored and every series is tagged with the server name, or its url.
+	## Servers without secret_key use the plugin secret_key. Other options apply to every server, except scalelite_mode,
+	## probe, backfill, webhooks_listen and debug_memory_stats, not run per server. startup_check checks every server
+	# [[inputs.bigbluebutton.servers]]
+	#   name = "bbb1"
+	#   url = "https://bbb1.example.com"
+	#   secret_key = ""
+	#   path_prefix = "/bigbluebutton"
 `
 
-// Init initialize the BigBlueButton struct with precalculated data
+// Init initialize the BigBlueButton struct with precalculated data. Init rewrites the plugin state in place and must
+// not run while Gather is running. After Init, only the BigBlueSwarm sync changes the servers, at gather time
 func (b *BigBlueButton) Init() error {
-	if b.SecretKey == "" {
+	b.migrateDeprecatedOptions()
+
+	if err := b.resolveSecrets(); err != nil {
+		return err
+	}
+
+	if len(b.Servers) > 0 {
+		return b.initServers()
+	}
+
+	if b.BigBlueSwarmURL != "" {
+		return b.initBigBlueSwarm()
+	}
+
+	if b.SecretKeyFile != "" {
+		if _, err := b.loadSecretKeyFile(); err != nil {
+			return fmt.Errorf("secret_key_file: %s", err)
+		}
+	}
+
+	keys := b.secretKeys()
+	if len(keys) == 0 {
 		return fmt.Errorf("BigBlueButton secret key is required")
 	}
+	// the secret key accepted by the server is kept when Init is called again
+	if !contains(keys, b.secret) {
+		b.secret = keys[0]
+	}
+
+	b.checksumAuto = b.ChecksumAlgorithm == "auto"
+	b.checksumNegotiated = false
+	b.hashAlgorithm = b.ChecksumAlgorithm
+	if b.checksumAuto || b.hashAlgorithm == "" {
+		b.hashAlgorithm = "sha1"
+	}
+
+	if _, ok := checksumHashes[b.hashAlgorithm]; !ok {
+		return fmt.Errorf("unsupported checksum_algorithm %q, expected sha1, sha256, sha512 or auto", b.ChecksumAlgorithm)
+	}
 
 	if b.PathPrefix == "" {
 		b.PathPrefix = defaultPathPrefix
 	}
 
-	b.getMeetingsURL = b.getURL("getMeetings")
-	b.getRecordingsURL = b.getURL("getRecordings")
-	b.healthCheckURL = b.getHealthCheckURL()
-
-	tlsCfg, err := b.ClientConfig.TLSConfig()
+	u, err := parseServerURL(b.URL)
 	if err != nil {
 		return err
 	}
+	b.serverURL = u
+	b.stats = newSelfStats(u.Redacted())
 
-	proxy, err := b.HTTPProxy.Proxy()
-	if err != nil {
+	if err := b.checkHTTPS(); err != nil {
 		return err
 	}
 
-	transport := &http.Transport{
-		TLSClientConfig: tlsCfg,
-		Proxy:           proxy,
-	}
+	b.buildURLs()
 
-	b.client = &http.Client{
-		Transport: transport,
+	if err := b.initClient(); err != nil {
+		return err
 	}
 
-	return nil
-}
+	if b.RecordingFailureWindow == 0 {
+		b.RecordingFailureWindow = defaultRecordingFailureWindow
+	}
 
-// SampleConfig provides a sample config object
-func (b *BigBlueButton) SampleConfig() string {
-	return sampleConfig
-}
+	b.meetingIDPatterns = make(map[string]*regexp.Regexp, len(b.MeetingIDMetadata))
+	for md, pattern := range b.MeetingIDMetadata {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("meeting_id_metadata %s: %s", md, err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("meeting_id_metadata %s: pattern must contain a capture group", md)
+		}
+		b.meetingIDPatterns[b.normalizeMetadataKey(md)] = re
+	}
 
-// Description provides a simple description sentence that explain the plugin
-func (b *BigBlueButton) Description() string {
-	return "Gather BigBlueButton web conferencing server metrics"
-}
+	b.knownMetadataValues = make(map[string][]string, len(b.KnownMetadataValues))
+	for md, values := range b.KnownMetadataValues {
+		md = b.normalizeMetadataKey(md)
+		b.knownMetadataValues[md] = append(b.knownMetadataValues[md], values...)
+	}
 
-// Gather retrieve and publish metrics using the telegraf.Accumulator
-func (b *BigBlueButton) Gather(acc telegraf.Accumulator) error {
-	m, err := b.getMeetings()
-	if err != nil {
-		return err
+	b.tenantMap = nil
+	if b.TenantMapFile != "" {
+		if err := b.loadTenantMap(); err != nil {
+			return fmt.Errorf("tenant_map_file: %s", err)
+		}
 	}
 
-	r, err := b.getRecordings()
-	if err != nil {
-		return err
+	b.metadataRegexes = make([]*regexp.Regexp, len(b.MetadataValueRegex))
+	for i, mr := range b.MetadataValueRegex {
+		re, err := regexp.Compile(mr.Pattern)
+		if err != nil {
+			return fmt.Errorf("metadata_value_regex %s: %s", mr.Key, err)
+		}
+		b.metadataRegexes[i] = re
 	}
 
-	h, err := b.getHealCheck()
-	if err != nil {
-		return err
+	if b.metadataFilter, err = filter.NewIncludeExcludeFilter(b.MetadataInclude, b.MetadataExclude); err != nil {
+		return fmt.Errorf("metadata_include/metadata_exclude: %s", err)
 	}
 
-	rec := NewRecordFrom(m.Meetings.Values, r.Recordings.Values, *h)
-	acc.AddFields("bigbluebutton", toStringMapInterface(rec.ToMap()), make(map[string]string))
+	// Init can be called again to apply a new configuration, gathered state is kept
+	if b.recordings == nil {
+		b.recordings = newRecordingTracker(time.Duration(b.RecordingFailureWindow))
+	}
+	b.recordings.failureWindow = time.Duration(b.RecordingFailureWindow)
+	if b.uniqueUsers == nil {
+		b.uniqueUsers = newUniqueUsers()
+	}
+	if b.hourlyProfile == nil {
+		b.hourlyProfile = newHourlyProfile()
+	}
+	b.meetingInfoCache = map[string]cachedMeetingInfo{}
+	if b.probeMeetingID == "" {
+		b.probeMeetingID = newProbeMeetingID()
+	}
+	b.prefixResolved = false
+	b.resolvePathPrefix()
+	if b.checksumAuto {
+		b.negotiateChecksum()
+	}
 
-	if b.shouldGatheredByMetadata() {
-		recs := b.GetMetadataRecords(m, r, h)
-		for mname, mrecs := range recs {
-			for mval, rs := range mrecs {
-				tags := make(map[string]string)
-				tags[mname] = mval
-				acc.AddFields(mname, toStringMapInterface(rs.ToMap()), tags)
-			}
-		}
+	if b.StartupCheck {
+		return b.startupCheck()
 	}
 
 	return nil
 }
 
-// GetMetadataRecords parse responses and returns a map for record
-func (b *BigBlueButton) GetMetadataRecords(mr *MeetingsResponse, rr *RecordingsResponse, hr *HealthCheck) map[string]map[string]*Record {
-	type storage struct {
-		meetings   []Meeting
-		recordings []Recording
+// initClient creates the HTTP client from the TLS and proxy configuration
+func (b *BigBlueButton) initClient() error {
+	tlsCfg, err := b.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
 	}
 
-	store := map[string]map[string]*storage{}
-	res := map[string]map[string]*Record{}
+	proxy, err := b.HTTPProxy.Proxy()
+	if err != nil {
+		return err
+	}
 
-	createStorageIfNotExists := func(metadata string, key string) {
-		if _, ok := store[metadata]; !ok {
-			store[metadata] = map[string]*storage{}
-		}
+	transport := &http.Transport{
+		TLSClientConfig:       tlsCfg,
+		Proxy:                 proxy,
+		DialContext:           (&net.Dialer{Timeout: time.Duration(b.ConnectTimeout)}).DialContext,
+		ResponseHeaderTimeout: time.Duration(b.ReadTimeout),
+	}
 
-		mdmap := store[metadata]
-		if _, ok := mdmap[key]; !ok {
-			mdmap[key] = &storage{
-				meetings:   []Meeting{},
-				recordings: []Recording{},
-			}
+	if b.SSHTunnelHost != "" {
+		tunnel, err := b.newSSHTunnel()
+		if err != nil {
+			return err
 		}
+		transport.DialContext = tunnel.DialContext
 	}
 
-	for _, md := range b.GatherByMetadata {
-		for _, m := range mr.Meetings.Values {
-			m.ParseMetadata()
-			if !m.ContainsMetadata(md) {
-				continue
-			}
-
-			val := m.GetMetadata(md)
-			createStorageIfNotExists(md, val)
+	if b.Timeout == 0 {
+		b.Timeout = defaultTimeout
+	}
 
-			s := store[md][val]
-			s.meetings = append(s.meetings, m)
-		}
+	if b.client == nil {
+		b.client = &atomic.Pointer[http.Client]{}
+	}
+	previous := b.client.Swap(&http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(b.Timeout),
+	})
+	if previous != nil {
+		previous.CloseIdleConnections()
+	}
+	b.rateLimit = &rateLimitState{}
 
-		for _, r := range rr.Recordings.Values {
-			r.ParseMetadata()
-			if !r.ContainsMetadata(md) {
-				continue
-			}
+	return nil
+}
 
-			val := r.GetMetadata(md)
-			createStorageIfNotExists(md, val)
+// endpoints are the signed API urls of a server. They are rebuilt as a whole, never modified in place, and swapped
+// atomically, so that concurrent requests read a consistent snapshot while the path prefix, checksum algorithm or
+// secret is being resolved
+type endpoints struct {
+	getMeetings         string
+	getRecordings       string
+	getRecordingsStates []string
+	healthCheck         string
+}
 
-			s := store[md][val]
-			s.recordings = append(s.recordings, r)
-		}
+// buildURLs precalculates the API urls from the current path prefix and checksum algorithm
+func (b *BigBlueButton) buildURLs() {
+	e := &endpoints{
+		getMeetings:   b.getURL("getMeetings"),
+		getRecordings: b.getURL("getRecordings"),
+		healthCheck:   b.getHealthCheckURL(),
+	}
+	for _, state := range b.RecordingsStates {
+		e.getRecordingsStates = append(e.getRecordingsStates, b.getRecordingsStateURL(state))
+	}
+	if b.endpoints == nil {
+		b.endpoints = &atomic.Pointer[endpoints]{}
+	}
+	b.endpoints.Store(e)
+}
 
+// resolvePathPrefix tries each configured path prefix in order and keeps the first one answering the health check.
+// If none answers, the first prefix is used and resolution is retried on next gather
+func (b *BigBlueButton) resolvePathPrefix() {
+	if len(b.PathPrefixes) == 0 {
+		b.prefixResolved = true
+		return
 	}
 
-	for key, val := range store {
-		res[key] = map[string]*Record{}
-		for mk, mval := range val {
-			res[key][mk] = NewRecordFrom(mval.meetings, mval.recordings, *hr)
+	for _, prefix := range b.PathPrefixes {
+		b.PathPrefix = prefix
+		b.buildURLs()
+		if h, err := b.getHealCheck(); err == nil && h.ReturnCode == "SUCCESS" {
+			b.prefixResolved = true
+			return
 		}
 	}
 
-	return res
+	b.PathPrefix = b.PathPrefixes[0]
+	b.buildURLs()
 }
 
-// BigBlueButton uses an authentication based on a SHA1 checksum processed from api call name and server secret key
-func (b *BigBlueButton) checksum(apiCallName string) []byte {
-	hash := sha1.New()
-	hash.Write([]byte(fmt.Sprintf("%s%s", apiCallName, b.SecretKey)))
-	return hash.Sum(nil)
+// negotiateChecksum selects the first checksum algorithm accepted by the server, trying sha1 then sha256 and sha512.
+// When the server cannot be reached, sha1 is used and the negotiation is retried on next gather
+func (b *BigBlueButton) negotiateChecksum() {
+	rejected := 0
+	for _, algorithm := range negotiatedChecksumAlgorithms {
+		b.hashAlgorithm = algorithm
+		b.buildURLs()
+		_, err := b.getMeetings()
+		if err == nil {
+			b.checksumNegotiated = true
+			return
+		}
+		if !isAuthError(err) {
+			break
+		}
+		rejected++
+	}
+
+	// every algorithm rejected means a wrong secret, reported by gathers through auth_ok
+	b.checksumNegotiated = rejected == len(negotiatedChecksumAlgorithms)
+	b.hashAlgorithm = negotiatedChecksumAlgorithms[0]
+	b.buildURLs()
 }
 
-func (b *BigBlueButton) getURL(apiCallName string) string {
-	endpoint := fmt.Sprintf("%s/api/%s", b.PathPrefix, apiCallName)
-	return fmt.Sprintf("%s%s?checksum=%x", b.URL, endpoint, b.checksum(apiCallName))
+// SampleConfig provides a sample config object
+func (b *BigBlueButton) SampleConfig() string {
+	return sampleConfig
 }
 
-func (b *BigBlueButton) getHealthCheckURL() string {
-	endpoint := fmt.Sprintf("%s/api", b.PathPrefix)
-	return fmt.Sprintf("%s%s", b.URL, endpoint)
+// Description provides a simple description sentence that explain the plugin
+func (b *BigBlueButton) Description() string {
+	return "Gather BigBlueButton web conferencing server metrics"
 }
 
-// Call BBB server api
-func (b *BigBlueButton) api(url string) ([]byte, error) {
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+// Gather retrieve and publish metrics using the telegraf.Accumulator
+func (b *BigBlueButton) Gather(acc telegraf.Accumulator) error {
+	if b.Precision > 0 {
+		acc = &precisionAccumulator{Accumulator: acc, precision: time.Duration(b.Precision)}
 	}
 
-	if b.Username != "" || b.Password != "" {
-		request.SetBasicAuth(b.Username, b.Password)
+	if b.SchemaVersionTag {
+		acc = &taggedAccumulator{Accumulator: acc, tags: map[string]string{"schema_version": schemaVersion}}
 	}
 
-	resp, err := b.client.Do(request)
-
-	if err != nil || resp.StatusCode != 200 {
-		return nil, fmt.Errorf("error getting bbb metrics: %s status %d", err, resp.StatusCode)
+	if b.DebugMemoryStats {
+		defer gatherMemoryStats(acc, readMemoryStats())
 	}
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	if b.webhooks != nil {
+		b.gatherWebhooks(acc)
+	}
 
-	if err != nil {
-		return nil, err
+	if b.BigBlueSwarmURL != "" {
+		return b.gatherBigBlueSwarm(acc)
 	}
 
-	return body, nil
-}
+	if len(b.servers.load()) > 0 {
+		b.gatherServers(acc)
+		return nil
+	}
 
-func (b *BigBlueButton) getMeetings() (*MeetingsResponse, error) {
-	body, err := b.api(b.getMeetingsURL)
-	if err != nil {
-		return nil, err
+	if b.OfflineBackoff > 0 && time.Now().Before(b.nextAttempt) {
+		return nil
 	}
 
-	var response MeetingsResponse
-	err = xml.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+	start := time.Now()
+	err := b.gather(acc)
+	b.stats.gathered(time.Since(start))
+	if err != nil && b.SecretKeyFile != "" {
+		if rerr := b.reloadSecretKeyFile(); rerr != nil {
+			acc.AddError(fmt.Errorf("reading secret_key_file: %s", rerr))
+		}
+	}
+	b.updateBackoff(err)
+	if err != nil && b.EmitEvents {
+		b.gatherEvents(acc, false, 0)
 	}
+	return err
+}
 
-	return &response, nil
+func readMemoryStats() *runtime.MemStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return &ms
 }
 
-func (b *BigBlueButton) getRecordings() (*RecordingsResponse, error) {
-	body, err := b.api(b.getRecordingsURL)
-	if err != nil {
-		return nil, err
+// gatherMemoryStats emits the bigbluebutton_debug measurement: bytes and objects allocated since before, and the
+// largest heap in use sampled before and after the gather. Memory statistics are process wide, allocations of other
+// plugins gathering concurrently are included
+func gatherMemoryStats(acc telegraf.Accumulator, before *runtime.MemStats) {
+	after := readMemoryStats()
+	peak := before.HeapInuse
+	if after.HeapInuse > peak {
+		peak = after.HeapInuse
 	}
 
-	var response RecordingsResponse
-	err = xml.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+	acc.AddFields("bigbluebutton_debug", map[string]interface{}{
+		"gather_alloc_bytes":    after.TotalAlloc - before.TotalAlloc,
+		"gather_mallocs":        after.Mallocs - before.Mallocs,
+		"heap_inuse_peak_bytes": peak,
+	}, map[string]string{})
+}
+
+// updateBackoff computes the next gather attempt. While the server is failing, the delay between attempts doubles
+// from offline_backoff up to offline_backoff_max. It resets on success
+func (b *BigBlueButton) updateBackoff(err error) {
+	if b.OfflineBackoff <= 0 {
+		return
 	}
 
-	return &response, nil
-}
+	if err == nil {
+		b.backoff = 0
+		b.nextAttempt = time.Time{}
+		return
+	}
 
-func (b *BigBlueButton) getHealCheck() (*HealthCheck, error) {
-	body, err := b.api(b.getHealthCheckURL())
-	if err != nil {
-		return nil, err
+	if b.backoff == 0 {
+		b.backoff = time.Duration(b.OfflineBackoff)
+	} else {
+		b.backoff *= 2
 	}
 
-	var response HealthCheck
-	err = xml.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+	if b.OfflineBackoffMax > 0 && b.backoff > time.Duration(b.OfflineBackoffMax) {
+		b.backoff = time.Duration(b.OfflineBackoffMax)
 	}
 
-	return &response, nil
+	b.nextAttempt = time.Now().Add(b.backoff)
 }
 
-func (b *BigBlueButton) shouldGatheredByMetadata() bool {
-	return len(b.GatherByMetadata) > 0
+func (b *BigBlueButton) gather(acc telegraf.Accumulator) error {
+	if !b.prefixResolved {
+		b.resolvePathPrefix()
+	}
+	if b.checksumAuto && !b.checksumNegotiated {
+		b.negotiateChecksum()
+	}
+
+	start := time.Now()
+	skipped := uint64(0)
+	b.rateLimit.Reset()
+
+	// getMeetings, the health check and getRecordings are independent and fetched concurrently
+	var m *MeetingsResponse
+	var h *HealthCheck
+	var fetched *RecordingsResponse
+	var healthErr, recordingsErr error
+	var meetingsTime, healthTime, recordingsTime time.Duration
+	var g errgroup.Group
+	tcpReachable := false
+	if b.TCPCheck {
+		g.Go(func() error {
+			tcpReachable = b.tcpReachable()
+			return nil
+		})
+	}
+	g.Go(func() error {
+		defer measure(time.Now(), &meetingsTime)
+		var err error
+		m, err = b.getMeetings()
+		return err
+	})
+	gatherHealth := b.wantsAnyField(isHealthField)
+	if gatherHealth {
+		g.Go(func() error {
+			defer measure(time.Now(), &healthTime)
+			h, healthErr = b.getHealCheck()
+			return nil
+		})
+	}
+	refreshRecordings := b.wantsAnyField(isRecordingField) &&
+		(b.lastRecordings == nil || time.Since(b.recordingsFetchedAt) >= time.Duration(b.RecordingsCacheInterval))
+	// recordings are an optional collector, their fetch is cut once the time budget is used
+	budget, cancel := b.timeBudgetContext(start)
+	defer cancel()
+	if refreshRecordings {
+		g.Go(func() error {
+			defer measure(time.Now(), &recordingsTime)
+			fetched, recordingsErr = b.getRecordings(budget)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		if isAuthError(err) && b.rotateSecretKey() {
+			return b.gather(acc)
+		}
+
+		// the server is reported offline even without tcp_check, so that a down api shows in the online series
+		failed := map[string]interface{}{"online": uint64(0), "api_online": uint64(0)}
+		if isAuthError(err) {
+			failed["auth_ok"] = uint64(0)
+		}
+		if b.TCPCheck {
+			failed["tcp_reachable"] = boolToUint64(tcpReachable)
+		}
+		acc.AddFields("bigbluebutton", failed, map[string]string{})
+		return err
+	}
+
+	healthy := true
+	if healthErr != nil {
+		acc.AddError(fmt.Errorf("getting health check: %s", healthErr))
+		h = &HealthCheck{}
+		healthy = false
+	} else if !gatherHealth {
+		h = &HealthCheck{}
+		healthy = false
+	}
+
+	if b.EnrichMeetingInfo {
+		if b.withinTimeBudget(start) {
+			b.enrichMeetings(acc, m.Meetings.Values)
+		} else {
+			skipped++
+		}
+	}
+
+	r := b.lastRecordings
+	recordingsFailed := false
+	if recordingsErr != nil {
+		if budget.Err() != nil {
+			skipped++
+		} else {
+			acc.AddError(fmt.Errorf("getting recordings: %s", recordingsErr))
+		}
+		recordingsFailed = true
+		// previous recordings are only reported in place of failed ones when recordings_cache_interval accepts
+		// stale recordings, and for at most one more interval
+		if !b.recordingsCacheValid() {
+			r = nil
+		}
+	} else if refreshRecordings {
+		r = fetched
+		b.lastRecordings = r
+		b.recordingsFetchedAt = time.Now()
+	}
+
+	// Without any recordings data, recording fields and collectors are omitted rather than reported as zero
+	hasRecordings := r != nil
+	if !hasRecordings {
+		r = &RecordingsResponse{}
+	}
+
+	if b.ReturnCodeTag {
+		returnCode := "SUCCESS"
+		if (gatherHealth && (!healthy || h.ReturnCode != "SUCCESS")) || recordingsFailed || m.ReturnCode != "SUCCESS" || (hasRecordings && r.ReturnCode != "SUCCESS") {
+			returnCode = "FAILED"
+		}
+		acc = &taggedAccumulator{Accumulator: acc, tags: map[string]string{"returncode": returnCode}}
+	}
+
+	if b.ResolvedIPTag {
+		if ip, err := resolveHost(b.serverURL.Hostname()); err != nil {
+			acc.AddError(fmt.Errorf("resolving %s: %s", b.serverURL.Hostname(), err))
+		} else {
+			acc = &taggedAccumulator{Accumulator: acc, tags: map[string]string{"resolved_ip": ip}}
+		}
+	}
+
+	rec := NewRecordFrom(m.Meetings.Values, nil, *h)
+	if r.Summary != nil {
+		rec.AddRecordingMetrics(r.Summary)
+	}
+	fields := b.recordFields(rec)
+	if healthy {
+		b.addVersionFields(h, fields)
+	}
+	fields["parse_errors"] = m.ParseErrors
+	fields["auth_ok"] = boolToUint64(!isAuthError(recordingsErr))
+	if hasRecordings {
+		if !recordingsFailed && b.listsPendingRecordings() {
+			fields["recordings_published_delta"], fields["recordings_failed"] = b.recordings.Update(r.Recordings.Values, time.Now())
+		}
+		if b.GatherRecordingTotals {
+			addRecordingTotalsFields(r.Recordings.Values, fields)
+		}
+		if b.GatherPendingRecordingAge {
+			addPendingRecordingAgeFields(r.Recordings.Values, time.Now(), fields)
+		}
+	} else {
+		deleteRecordingFields(rec, fields)
+	}
+	if b.GatherMeetingPolicies {
+		addMeetingPoliciesFields(m.Meetings.Values, fields)
+	}
+	if b.GatherAudioStates {
+		addAudioStatesFields(m.Meetings.Values, fields)
+	}
+	if b.GatherVideoPublishers {
+		addVideoPublishersFields(m.Meetings.Values, fields)
+	}
+	if b.GatherGuests {
+		addGuestsFields(m.Meetings.Values, b.EnrichMeetingInfo, fields)
+	}
+	if b.GatherMeetingAges {
+		addMeetingAgesFields(m.Meetings.Values, time.Now(), fields)
+	}
+	if b.GatherSeatUtilization {
+		addSeatsFields(m.Meetings.Values, fields)
+	}
+	if b.GatherDistinctOrigins {
+		b.addDistinctOriginsFields(m.Meetings.Values, fields)
+	}
+	if b.GatherEmptyMeetings {
+		addEmptyMeetingsFields(m.Meetings.Values, fields)
+	}
+	if b.estimatesBandwidth() {
+		bandwidth := 0.0
+		for _, meeting := range m.Meetings.Values {
+			bandwidth += b.estimatedBandwidth(meeting)
+		}
+		fields["estimated_bandwidth_mbps"] = bandwidth
+	}
+	if b.EmitCounters {
+		fields["participant_minutes_total"], fields["meeting_starts_total"] = b.counters.Update(m.Meetings.Values, time.Now())
+	}
+	if b.GatherUniqueUsers {
+		b.addUniqueUsersFields(m.Meetings.Values, fields)
+	}
+	if b.GatherHourlyProfile {
+		for k, v := range b.hourlyProfile.Add(time.Now(), rec.Meetings, rec.Participants) {
+			fields[k] = v
+		}
+	}
+	if b.PlaybackURLTemplate != "" && hasRecordings {
+		if !b.withinTimeBudget(start) {
+			skipped++
+		} else if reachable, ok := b.probePlayback(r.Recordings.Values); ok {
+			fields["playback_reachable"] = reachable
+		}
+	}
+	fields["rate_limited"] = boolToUint64(b.rateLimit.Limited())
+	if b.TCPCheck {
+		fields["tcp_reachable"] = boolToUint64(tcpReachable)
+		fields["api_online"] = rec.Online
+	}
+	if b.GatherResponseTimes {
+		fields["meetings_response_time_ms"] = milliseconds(meetingsTime)
+		if healthy {
+			fields["healthcheck_response_time_ms"] = milliseconds(healthTime)
+		}
+		if refreshRecordings && !recordingsFailed {
+			fields["recordings_response_time_ms"] = milliseconds(recordingsTime)
+		}
+	}
+	if b.CollectorTimeBudget > 0 {
+		fields["collectors_skipped"] = skipped
+	}
+	if h.HasClockSkew {
+		fields["clock_skew_seconds"] = int64(h.ClockSkew.Round(time.Second) / time.Second)
+	}
+	tags := messageKeyTags(m, r)
+	if version := h.ServerVersion(); version != "" {
+		tags["version"] = version
+	}
+	if b.checksumAuto {
+		tags["checksum_algorithm"] = b.hashAlgorithm
+	}
+	b.filterFields(fields)
+	// an online server without running meeting is idle, skip_idle omits its points
+	idle := b.SkipIdle && rec.Online == 1 && rec.Meetings == 0
+	if !idle {
+		acc.AddFields("bigbluebutton", fields, tags)
+	}
+
+	if b.EmitEvents {
+		b.gatherEvents(acc, rec.Online == 1, rec.Meetings)
+	}
+
+	if idle {
+		return nil
+	}
+
+	if b.RecordingsByState && hasRecordings {
+		for state, count := range RecordingStateCounts(r.Recordings.Values) {
+			acc.AddFields("bigbluebutton_recordings", map[string]interface{}{"recordings": count}, map[string]string{"state": state})
+		}
+	}
+
+	if b.RecordingsByAge && hasRecordings {
+		for age, count := range RecordingAgeCounts(r.Recordings.Values, time.Now()) {
+			acc.AddFields("bigbluebutton_recordings_by_age", map[string]interface{}{"recordings": count}, map[string]string{"age": age})
+		}
+	}
+
+	if b.GatherGuestPolicies {
+		for policy, count := range GuestPolicyCounts(m.Meetings.Values) {
+			acc.AddFields("bigbluebutton_guest_policy", map[string]interface{}{"meetings": count}, map[string]string{"policy": policy})
+		}
+	}
+
+	b.gatherVoiceBridgeRanges(acc, m.Meetings.Values)
+
+	if b.Probe {
+		b.gatherProbe(acc)
+	}
+
+	if b.ScaleliteMode {
+		if err := b.gatherScalelite(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	if b.WebManagementURL != "" {
+		if err := b.gatherWeb(acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+
+	// per meeting and per metadata points count breakout rooms in their parent meeting
+	grouped := m
+	if b.GroupBreakoutsWithParent {
+		g := *m
+		g.Meetings.Values = groupBreakouts(m.Meetings.Values)
+		grouped = &g
+	}
+
+	if b.PerMeetingMetrics {
+		b.gatherMeetings(acc, grouped.Meetings.Values)
+	}
+
+	if b.PerRecordingMetrics && hasRecordings {
+		b.gatherRecordings(acc, r.Recordings.Values)
+	}
+
+	if b.Backfill {
+		if err := b.gatherBackfill(acc); err != nil {
+			acc.AddError(fmt.Errorf("backfilling recordings: %s", err))
+		}
+	}
+
+	if b.GroupByOriginServer {
+		origin := b.normalizeMetadataKey(originServerMetadata)
+		origins := b.groupByMetadata([]string{origin}, grouped, r, h)[origin]
+		for origin, rs := range origins {
+			fields := b.recordFields(rs)
+			if !hasRecordings {
+				deleteRecordingFields(rs, fields)
+			}
+			acc.AddFields("bigbluebutton_origin", fields, map[string]string{"origin_server": origin})
+		}
+	}
+
+	if err := b.refreshMetadataFile(time.Now()); err != nil {
+		acc.AddError(fmt.Errorf("reading gather_by_metadata_file: %s", err))
+	}
+
+	if b.TenantMapFile != "" && b.TenantMapFileReload {
+		if err := b.loadTenantMap(); err != nil {
+			acc.AddError(fmt.Errorf("reading tenant_map_file: %s", err))
+		}
+	}
+
+	if b.shouldGatheredByMetadata() {
+		recs := b.GetMetadataRecords(grouped, r, h)
+		for mname, mrecs := range recs {
+			names := strings.Split(mname, combinedMetadataSeparator)
+			for mval, rs := range mrecs {
+				values := strings.Split(mval, combinedMetadataSeparator)
+				tags := make(map[string]string)
+				for i, name := range names {
+					tags[name] = values[i]
+				}
+				fields := b.recordFields(rs)
+				if !hasRecordings {
+					deleteRecordingFields(rs, fields)
+				}
+				acc.AddFields(b.metadataMeasurement(strings.Join(names, "_")), fields, tags)
+			}
+		}
+	}
+
+	return nil
+}
+
+// metadataMeasurement returns the measurement name of a gather_by_metadata key, following
+// metadata_measurement_template when set
+func (b *BigBlueButton) metadataMeasurement(key string) string {
+	if b.MetadataMeasurementTemplate == "" {
+		return key
+	}
+	return strings.ReplaceAll(b.MetadataMeasurementTemplate, "{{key}}", key)
+}
+
+// listsPendingRecordings returns true when getRecordings lists recordings still processing, which
+// recordings_published_delta and recordings_failed are computed from. By default only published recordings are listed
+func (b *BigBlueButton) listsPendingRecordings() bool {
+	return b.GatherRecordingStates || contains(b.RecordingsStates, "processing") || contains(b.RecordingsStates, "processed")
+}
+
+// recordingsCacheValid returns true when recordings_cache_interval is set and the previous recordings were fetched
+// less than two intervals ago
+func (b *BigBlueButton) recordingsCacheValid() bool {
+	return b.RecordingsCacheInterval > 0 && b.lastRecordings != nil &&
+		time.Since(b.recordingsFetchedAt) < 2*time.Duration(b.RecordingsCacheInterval)
+}
+
+// measure stores the time elapsed since start in d
+func measure(start time.Time, d *time.Duration) {
+	*d = time.Since(start)
+}
+
+// milliseconds returns the duration in milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// deleteRecordingFields removes the fields computed from recordings
+func deleteRecordingFields(rec *Record, fields map[string]interface{}) {
+	delete(fields, "recordings")
+	delete(fields, "published_recordings")
+	for k := range rec.RecordingStatesMap() {
+		delete(fields, k)
+	}
+	for k := range rec.PlaybackFormatsMap() {
+		delete(fields, k)
+	}
+}
+
+// isRecordingField returns true for the bigbluebutton measurement fields computed from getRecordings
+func isRecordingField(field string) bool {
+	if field == "active_recordings" {
+		return false
+	}
+	return strings.Contains(field, "recordings") || field == "playback_reachable"
+}
+
+// isHealthField returns true for the bigbluebutton measurement fields computed from the health check
+func isHealthField(field string) bool {
+	switch field {
+	case "online", "version_major", "version_minor", "healthcheck_response_time_ms", "clock_skew_seconds":
+		return true
+	}
+	return false
+}
+
+// wantsAnyField check if one of the fields allowed by the fields option matches the predicate.
+// Without fields option, every field is wanted
+func (b *BigBlueButton) wantsAnyField(match func(string) bool) bool {
+	if len(b.Fields) == 0 {
+		return true
+	}
+	for _, f := range b.Fields {
+		if match(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterFields removes the bigbluebutton measurement fields not allowed by the fields option
+func (b *BigBlueButton) filterFields(fields map[string]interface{}) {
+	if len(b.Fields) == 0 {
+		return
+	}
+	for k := range fields {
+		if !contains(b.Fields, k) {
+			delete(fields, k)
+		}
+	}
+}
+
+// timeBudgetContext returns a context done once the gather has used 80% of the configured collector time budget,
+// bounding the optional collectors requests
+func (b *BigBlueButton) timeBudgetContext(start time.Time) (context.Context, context.CancelFunc) {
+	if b.CollectorTimeBudget <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithDeadline(context.Background(), start.Add(time.Duration(b.CollectorTimeBudget)*8/10))
+}
+
+// withinTimeBudget check if optional collectors can still run. Optional collectors are skipped once
+// the gather has used 80% of the configured collector time budget
+func (b *BigBlueButton) withinTimeBudget(start time.Time) bool {
+	if b.CollectorTimeBudget <= 0 {
+		return true
+	}
+
+	return time.Since(start) < time.Duration(b.CollectorTimeBudget)*8/10
+}
+
+// GetMetadataRecords parse responses and returns a map for record
+func (b *BigBlueButton) GetMetadataRecords(mr *MeetingsResponse, rr *RecordingsResponse, hr *HealthCheck) map[string]map[string]*Record {
+	keys := b.metadataKeys()
+	if b.GatherByMetadataCombined && len(keys) > 1 {
+		keys = []string{strings.Join(keys, combinedMetadataSeparator)}
+	}
+
+	res := b.groupByMetadata(keys, mr, rr, hr)
+	for _, key := range keys {
+		for _, val := range b.knownMetadataValues[key] {
+			if res[key] == nil {
+				res[key] = map[string]*Record{}
+			}
+			if _, ok := res[key][val]; !ok {
+				res[key][val] = NewRecordFrom([]Meeting{}, []Recording{}, *hr)
+			}
+		}
+	}
+
+	return res
+}
+
+// groupByMetadata returns records grouped by metadata key and value for the given metadata keys
+func (b *BigBlueButton) groupByMetadata(keys []string, mr *MeetingsResponse, rr *RecordingsResponse, hr *HealthCheck) map[string]map[string]*Record {
+	type storage struct {
+		meetings   []Meeting
+		recordings []Recording
+	}
+
+	store := map[string]map[string]*storage{}
+	res := map[string]map[string]*Record{}
+
+	createStorageIfNotExists := func(metadata string, key string) {
+		if _, ok := store[metadata]; !ok {
+			store[metadata] = map[string]*storage{}
+		}
+
+		mdmap := store[metadata]
+		if _, ok := mdmap[key]; !ok {
+			mdmap[key] = &storage{
+				meetings:   []Meeting{},
+				recordings: []Recording{},
+			}
+		}
+	}
+
+	// metadata is parsed once per meeting and recording, then looked up for every key
+	for _, m := range mr.Meetings.Values {
+		b.parseMetadata(&m.MetadataStruct, m.MeetingID)
+		for _, md := range keys {
+			val, ok := metadataValue(&m.MetadataStruct, md, b.MetadataUnknownValue)
+			if !ok || !b.metadataValueAllowed(val) {
+				continue
+			}
+			val = b.tenantName(md, val)
+
+			createStorageIfNotExists(md, val)
+
+			s := store[md][val]
+			s.meetings = append(s.meetings, m)
+		}
+	}
+
+	for _, r := range rr.Recordings.Values {
+		b.parseMetadata(&r.MetadataStruct, r.MeetingID)
+		for _, md := range keys {
+			val, ok := metadataValue(&r.MetadataStruct, md, b.MetadataUnknownValue)
+			if !ok || !b.metadataValueAllowed(val) {
+				continue
+			}
+			val = b.tenantName(md, val)
+
+			createStorageIfNotExists(md, val)
+
+			s := store[md][val]
+			s.recordings = append(s.recordings, r)
+		}
+	}
+
+	type job struct {
+		metadata string
+		value    string
+		storage  *storage
+	}
+
+	jobs := make(chan job)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < b.metadataWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				rec := NewRecordFrom(j.storage.meetings, j.storage.recordings, *hr)
+				mu.Lock()
+				res[j.metadata][j.value] = rec
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for key := range store {
+		res[key] = map[string]*Record{}
+	}
+	for key, val := range store {
+		for mk, mval := range val {
+			jobs <- job{metadata: key, value: mk, storage: mval}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return res
+}
+
+// metadataValueAllowed check the metadata value, or each value of combined metadata, against metadata_include
+// and metadata_exclude
+func (b *BigBlueButton) metadataValueAllowed(val string) bool {
+	if b.metadataFilter == nil {
+		return true
+	}
+	for _, v := range strings.Split(val, combinedMetadataSeparator) {
+		if !b.metadataFilter.Match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// maxConcurrentRequests returns the number of getMeetingInfo calls allowed in flight at once
+func (b *BigBlueButton) maxConcurrentRequests() int {
+	if b.MaxConcurrentRequests > 0 {
+		return b.MaxConcurrentRequests
+	}
+	return defaultMaxConcurrentRequests
+}
+
+// metadataWorkers returns the number of goroutines computing metadata group records
+func (b *BigBlueButton) metadataWorkers() int {
+	if b.MetadataWorkers > 0 {
+		return b.MetadataWorkers
+	}
+	return defaultMetadataWorkers
+}
+
+// parseMetadata parses the metadata and derives missing metadata configured in meeting_id_metadata
+// from the first capture group of their pattern matched against the external meeting id
+func (b *BigBlueButton) parseMetadata(m *MetadataStruct, meetingID string) {
+	m.ParseMetadata()
+	b.normalizeMetadata(m)
+	for md, re := range b.meetingIDPatterns {
+		if m.ContainsMetadata(md) {
+			continue
+		}
+
+		if match := re.FindStringSubmatch(meetingID); match != nil && match[1] != "" {
+			m.ParsedMetadata[md] = match[1]
+		}
+	}
+
+	for i, mr := range b.MetadataValueRegex {
+		key := b.normalizeMetadataKey(mr.Key)
+		if v, ok := m.ParsedMetadata[key]; ok {
+			m.ParsedMetadata[key] = b.metadataRegexes[i].ReplaceAllString(v, mr.Replacement)
+		}
+	}
+}
+
+// resolveHost returns the first address the host resolves to, or the host itself when it is an IP address
+func resolveHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no address found")
+	}
+
+	return addrs[0].IP.String(), nil
+}
+
+// parseServerURL validates the configured server url. IPv6 literal hosts must be enclosed in brackets
+func parseServerURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BigBlueButton url %q: %s", raw, err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid BigBlueButton url %q: scheme must be http or https", raw)
+	}
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid BigBlueButton url %q: missing host", raw)
+	}
+
+	if !strings.HasPrefix(u.Host, "[") && strings.Count(u.Host, ":") > 1 {
+		return nil, fmt.Errorf("invalid BigBlueButton url %q: IPv6 literal host must be enclosed in brackets", raw)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u, nil
+}
+
+// checkHTTPS ensures the server url uses https when require_https is enabled. Loopback hosts are allowed over plain http
+func (b *BigBlueButton) checkHTTPS() error {
+	if !b.RequireHTTPS {
+		return nil
+	}
+
+	if b.serverURL.Scheme == "https" || isLoopbackHost(b.serverURL.Hostname()) {
+		return nil
+	}
+
+	return fmt.Errorf("BigBlueButton url %q does not use https while require_https is enabled", b.URL)
+}
+
+// tcpReachable check that a TCP connection to the server url host can be established, within connect_timeout,
+// or timeout when not set
+func (b *BigBlueButton) tcpReachable() bool {
+	port := b.serverURL.Port()
+	if port == "" {
+		port = "80"
+		if b.serverURL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	timeout := time.Duration(b.ConnectTimeout)
+	if timeout <= 0 {
+		timeout = time.Duration(b.Timeout)
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(b.serverURL.Hostname(), port), timeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// checksum signs an api call. BigBlueButton authenticates calls with a checksum processed from api call name, query string
+// and server secret key, hashed with the configured checksum_algorithm (sha1 by default, negotiated when auto)
+func (b *BigBlueButton) checksum(apiCallName string, query string) []byte {
+	newHash, ok := checksumHashes[b.hashAlgorithm]
+	if !ok {
+		newHash = sha1.New
+	}
+
+	hash := newHash()
+	hash.Write([]byte(fmt.Sprintf("%s%s%s", apiCallName, query, b.secret)))
+	return hash.Sum(nil)
+}
+
+// endpoint returns the server url joined with the path prefix and given path elements
+func (b *BigBlueButton) endpoint(elem ...string) *url.URL {
+	u := *b.serverURL
+	u.Path = path.Join(append([]string{"/", u.Path, b.PathPrefix}, elem...)...)
+	return &u
+}
+
+// getRecordingsStateURL returns the getRecordings url filtered on a single recording state
+func (b *BigBlueButton) getRecordingsStateURL(state string) string {
+	params := url.Values{}
+	for k, v := range b.ExtraParams["getRecordings"] {
+		params.Set(k, v)
+	}
+	params.Set("state", state)
+
+	return b.signedURL("getRecordings", params)
+}
+
+func (b *BigBlueButton) getURL(apiCallName string) string {
+	params := url.Values{}
+	if apiCallName == "getRecordings" && b.GatherRecordingStates {
+		params.Set("state", "any")
+	}
+	for k, v := range b.ExtraParams[apiCallName] {
+		params.Set(k, v)
+	}
+
+	return b.signedURL(apiCallName, params)
+}
+
+// signedURL returns the api call url with the given query parameters and their checksum
+func (b *BigBlueButton) signedURL(apiCallName string, params url.Values) string {
+	query := params.Encode()
+	u := b.endpoint("api", apiCallName)
+	u.RawQuery = fmt.Sprintf("checksum=%x", b.checksum(apiCallName, query))
+	if query != "" {
+		u.RawQuery = fmt.Sprintf("%s&%s", query, u.RawQuery)
+	}
+
+	return u.String()
+}
+
+func (b *BigBlueButton) getHealthCheckURL() string {
+	return b.endpoint("api").String()
+}
+
+// Call BBB server api
+func (b *BigBlueButton) api(url string) ([]byte, http.Header, error) {
+	body, header, err := b.apiStream(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return content, header, nil
+}
+
+// apiStream calls the api and returns the response body unread, for large responses decoded while they are read.
+// The caller closes the body
+func (b *BigBlueButton) apiStream(url string) (io.ReadCloser, http.Header, error) {
+	return b.apiStreamContext(context.Background(), url)
+}
+
+// apiStreamContext is apiStream bounded by ctx, reading the body included
+func (b *BigBlueButton) apiStreamContext(ctx context.Context, url string) (io.ReadCloser, http.Header, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if b.Username != "" || b.Password != "" {
+		request.SetBasicAuth(b.Username, b.Password)
+	}
+	if b.HostHeader != "" {
+		request.Host = b.HostHeader
+	}
+
+	start := time.Now()
+	logged := redactChecksum(url)
+	resp, err := b.client.Load().Do(request)
+	b.stats.request(err != nil || resp.StatusCode != http.StatusOK)
+	if err != nil {
+		b.debugf("GET %s: failed after %s: %s", logged, time.Since(start), err)
+		return nil, nil, fmt.Errorf("error getting bbb metrics: %s", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if delay, ok := b.rateLimit.Retry(resp.Header); ok {
+			b.debugf("GET %s: rate limited, retrying in %s", logged, delay)
+			resp.Body.Close()
+			time.Sleep(delay)
+			resp, err = b.client.Load().Do(request)
+			b.stats.request(err != nil || resp.StatusCode != http.StatusOK)
+			if err != nil {
+				b.debugf("GET %s: failed after %s: %s", logged, time.Since(start), err)
+				return nil, nil, fmt.Errorf("error getting bbb metrics: %s", err)
+			}
+		}
+	}
+
+	b.debugf("GET %s: status %d after %s", logged, resp.StatusCode, time.Since(start))
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("error getting bbb metrics: status %d", resp.StatusCode)
+	}
+
+	if b.Log != nil {
+		return &loggedBody{ReadCloser: resp.Body, plugin: b, url: logged, start: start}, resp.Header, nil
+	}
+	return resp.Body, resp.Header, nil
+}
+
+// rateLimitState tracks the HTTP 429 answers of the current gather. It is shared by concurrent requests
+type rateLimitState struct {
+	mu sync.Mutex
+	// limited reports whether a request was answered with HTTP 429
+	limited bool
+	// retried reports whether the Retry-After delay was already honored
+	retried bool
+}
+
+// Reset clears the state at the beginning of a gather
+func (s *rateLimitState) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited = false
+	s.retried = false
+}
+
+// Limited reports whether a request was answered with HTTP 429 since the last reset
+func (s *rateLimitState) Limited() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limited
+}
+
+// Retry records a HTTP 429 answer and returns the Retry-After delay to wait before retrying.
+// The delay is honored once per gather, and only up to maxRetryAfter
+func (s *rateLimitState) Retry(header http.Header) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited = true
+	delay, ok := retryAfter(header, time.Now())
+	if !ok || s.retried || delay > maxRetryAfter {
+		return 0, false
+	}
+
+	s.retried = true
+	return delay, true
+}
+
+// retryAfter returns the delay requested by the Retry-After header, given in seconds or as an HTTP date
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+	return 0, true
+}
+
+// apiError is returned when BigBlueButton answers HTTP 200 with a FAILED returncode
+type apiError struct {
+	apiCallName string
+	messageKey  string
+}
+
+func (e *apiError) Error() string {
+	if e.messageKey == "checksumError" {
+		return fmt.Sprintf("%s: checksum rejected (messageKey checksumError), check secret_key and checksum_algorithm", e.apiCallName)
+	}
+	return fmt.Sprintf("%s: returncode FAILED, messageKey %s", e.apiCallName, e.messageKey)
+}
+
+// isAuthError returns true when err is a BigBlueButton checksum rejection
+func isAuthError(err error) bool {
+	var e *apiError
+	return errors.As(err, &e) && e.messageKey == "checksumError"
+}
+
+func (b *BigBlueButton) getMeetings() (*MeetingsResponse, error) {
+	body, _, err := b.apiStream(b.endpoints.Load().getMeetings)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response, err := parseMeetingsResponse(body, b.usesMetadata())
+	if err != nil {
+		b.stats.parsed(0, 0, 1)
+		return nil, err
+	}
+	b.stats.parsed(len(response.Meetings.Values), 0, response.ParseErrors)
+	if response.ParseErrors > 0 {
+		b.debugf("getMeetings: skipped %d malformed meeting entries", response.ParseErrors)
+	}
+
+	if response.ReturnCode == "FAILED" {
+		return nil, &apiError{apiCallName: "getMeetings", messageKey: response.MessageKey}
+	}
+
+	return response, nil
+}
+
+// getRecordings fetches recordings. When recordings_states is set, each state is fetched concurrently
+// and the responses merged
+func (b *BigBlueButton) getRecordings(ctx context.Context) (*RecordingsResponse, error) {
+	e := b.endpoints.Load()
+	if len(e.getRecordingsStates) == 0 {
+		return b.getRecordingsFrom(ctx, e.getRecordings, b.keepsRecordings())
+	}
+
+	responses := make([]*RecordingsResponse, len(e.getRecordingsStates))
+	var g errgroup.Group
+	for i, u := range e.getRecordingsStates {
+		i, u := i, u
+		g.Go(func() error {
+			var err error
+			// recordings are kept to drop the ones returned for several states
+			responses[i], err = b.getRecordingsFrom(ctx, u, true)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return mergeRecordingsResponses(responses), nil
+}
+
+// mergeRecordingsResponses merges getRecordings responses. Recordings returned by several responses are kept once
+func mergeRecordingsResponses(responses []*RecordingsResponse) *RecordingsResponse {
+	merged := &RecordingsResponse{ReturnCode: "SUCCESS", Summary: NewRecord()}
+	seen := map[string]bool{}
+	for _, r := range responses {
+		for _, rec := range r.Recordings.Values {
+			if seen[rec.RecordID] {
+				continue
+			}
+			seen[rec.RecordID] = true
+			merged.Recordings.Values = append(merged.Recordings.Values, rec)
+		}
+	}
+	merged.Summary.ComputeRecordingMetrics(merged.Recordings.Values)
+	if len(merged.Recordings.Values) == 0 {
+		merged.MessageKey = "noRecordings"
+	}
+
+	return merged
+}
+
+// keepsRecordings returns true when an option needs the recordings themselves rather than their summed up metrics
+func (b *BigBlueButton) keepsRecordings() bool {
+	return b.usesMetadata() || b.PerRecordingMetrics || b.listsPendingRecordings() || b.GatherRecordingTotals ||
+		b.GatherPendingRecordingAge || b.PlaybackURLTemplate != "" || b.RecordingsByState || b.RecordingsByAge
+}
+
+// getRecordingsFrom fetches and decodes a getRecordings response. Recordings are only kept in the response when
+// keepRecordings is set, the response summary always holds their metrics
+func (b *BigBlueButton) getRecordingsFrom(ctx context.Context, u string, keepRecordings bool) (*RecordingsResponse, error) {
+	body, _, err := b.apiStreamContext(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	response, err := parseRecordingsResponse(body, b.usesMetadata(), keepRecordings)
+	if err != nil {
+		b.stats.parsed(0, 0, 1)
+		return nil, err
+	}
+	b.stats.parsed(0, int(response.Summary.Recordings), 0)
+
+	if response.ReturnCode == "FAILED" {
+		return nil, &apiError{apiCallName: "getRecordings", messageKey: response.MessageKey}
+	}
+
+	return response, nil
+}
+
+func (b *BigBlueButton) getHealCheck() (*HealthCheck, error) {
+	body, header, err := b.api(b.endpoints.Load().healthCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	var response HealthCheck
+	err = xml.Unmarshal(body, &response)
+	if err != nil {
+		b.stats.parsed(0, 0, 1)
+		return nil, err
+	}
+
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		response.ClockSkew = date.Sub(time.Now())
+		response.HasClockSkew = true
+	}
+
+	return &response, nil
+}
+
+// recordFields returns the record fields including the optional ones enabled by configuration
+func (b *BigBlueButton) recordFields(rec *Record) map[string]interface{} {
+	fields := toStringMapInterface(rec.ToMap())
+	if b.GatherClientTypes {
+		for k, v := range rec.ClientTypesMap() {
+			fields[k] = v
+		}
+	}
+	if b.GatherAttendeeRoles {
+		for k, v := range rec.RolesMap() {
+			fields[k] = v
+		}
+	}
+	if b.GatherRecordingStates {
+		for k, v := range rec.RecordingStatesMap() {
+			fields[k] = v
+		}
+	}
+	if b.GatherPlaybackFormats {
+		for k, v := range rec.PlaybackFormatsMap() {
+			fields[k] = v
+		}
+	}
+
+	return fields
+}
+
+// addUniqueUsersFields adds the estimated distinct users seen over the last hour and day
+func (b *BigBlueButton) addUniqueUsersFields(ms []Meeting, fields map[string]interface{}) {
+	users := []string{}
+	for _, m := range ms {
+		for _, a := range m.Attendees {
+			users = append(users, a.Identifier())
+		}
+	}
+
+	now := time.Now()
+	b.uniqueUsers.Add(now, users)
+	fields["unique_users_1h"] = b.uniqueUsers.Count(now, time.Hour)
+	fields["unique_users_24h"] = b.uniqueUsers.Count(now, 24*time.Hour)
+}
+
+// probePlayback sends a HEAD request to the playback url of the most recent published recording.
+// It returns false as second value when there is no published recording to probe
+func (b *BigBlueButton) probePlayback(rs []Recording) (uint64, bool) {
+	var latest *Recording
+	for i, r := range rs {
+		if r.Published && (latest == nil || r.StartTime > latest.StartTime) {
+			latest = &rs[i]
+		}
+	}
+
+	if latest == nil {
+		return 0, false
+	}
+
+	playbackURL := strings.ReplaceAll(b.PlaybackURLTemplate, "{{record_id}}", latest.RecordID)
+	request, err := http.NewRequest("HEAD", playbackURL, nil)
+	if err != nil {
+		return 0, true
+	}
+
+	resp, err := b.client.Load().Do(request)
+	if err != nil {
+		return 0, true
+	}
+	resp.Body.Close()
+
+	return boolToUint64(resp.StatusCode < 400), true
+}
+
+// addVersionFields adds version related fields. version_changed is 1 on the gather where the reported version differs
+// from the previous one, 0 otherwise
+func (b *BigBlueButton) addVersionFields(h *HealthCheck, fields map[string]interface{}) {
+	version := h.ServerVersion()
+	if b.ExpectedVersion != "" {
+		fields["version_mismatch"] = boolToUint64(!versionMatches(version, b.ExpectedVersion))
+	}
+
+	if version == "" {
+		return
+	}
+
+	if major, minor, ok := parseVersion(version); ok {
+		fields["version_major"] = major
+		fields["version_minor"] = minor
+	}
+
+	fields["version_changed"] = boolToUint64(b.lastVersion != "" && b.lastVersion != version)
+
+	b.lastVersion = version
+}
+
+// versionMatches returns true when the dotted components of expected prefix the version, so 2.7 matches 2.7.3
+// but not 2.70
+func versionMatches(version string, expected string) bool {
+	return version == expected || strings.HasPrefix(version, expected+".")
+}
+
+// parseVersion returns the major and minor numbers of a BigBlueButton version like 2.7.3
+func parseVersion(version string) (uint64, uint64, bool) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+
+	major, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	// keep the leading digits of the minor part, like 0 in 3.0-beta
+	digits := strings.IndexFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+	if digits >= 0 {
+		parts[1] = parts[1][:digits]
+	}
+	minor, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return major, minor, true
+}
+
+// addMeetingPoliciesFields adds the number of meetings with a duration cap, and with recording settings
+// when the server reports them, to audit rooms policy compliance
+func addMeetingPoliciesFields(ms []Meeting, fields map[string]interface{}) {
+	durationCapped := uint64(0)
+	autoStartRecording := uint64(0)
+	allowStartStopRecording := uint64(0)
+	reported := false
+	for _, m := range ms {
+		if m.Duration > 0 {
+			durationCapped++
+		}
+		if m.AutoStartRecording != nil {
+			reported = true
+			autoStartRecording += boolToUint64(*m.AutoStartRecording)
+		}
+		if m.AllowStartStopRecording != nil {
+			reported = true
+			allowStartStopRecording += boolToUint64(*m.AllowStartStopRecording)
+		}
+	}
+
+	fields["duration_capped_meetings"] = durationCapped
+	if reported {
+		fields["auto_start_recording_meetings"] = autoStartRecording
+		fields["allow_start_stop_recording_meetings"] = allowStartStopRecording
+	}
+
+	addMeetingFeatureField(ms, "webcams_only_for_moderator_meetings", func(m Meeting) *bool { return m.WebcamsOnlyForModerator }, fields)
+	addMeetingFeatureField(ms, "mute_on_start_meetings", func(m Meeting) *bool { return m.MuteOnStart }, fields)
+}
+
+// addMeetingFeatureField counts the meetings with a feature enabled. The field is omitted when no meeting
+// reports the feature setting
+func addMeetingFeatureField(ms []Meeting, field string, setting func(Meeting) *bool, fields map[string]interface{}) {
+	enabled := uint64(0)
+	reported := false
+	for _, m := range ms {
+		if v := setting(m); v != nil {
+			reported = true
+			enabled += boolToUint64(*v)
+		}
+	}
+
+	if reported {
+		fields[field] = enabled
+	}
+}
+
+// addAudioStatesFields adds the number of attendees per audio state. Attendees neither listening only nor in voice
+// are still in the echo test or joined without audio, a proxy for audio join failures
+func addAudioStatesFields(ms []Meeting, fields map[string]interface{}) {
+	voiceConnected := uint64(0)
+	listenOnly := uint64(0)
+	audioPending := uint64(0)
+	for _, m := range ms {
+		for _, a := range m.Attendees {
+			switch {
+			case a.IsListeningOnly:
+				listenOnly++
+			case a.HasJoinedVoice:
+				voiceConnected++
+			default:
+				audioPending++
+			}
+		}
+	}
+
+	fields["voice_connected_attendees"] = voiceConnected
+	fields["listen_only_attendees"] = listenOnly
+	fields["audio_pending_attendees"] = audioPending
+}
+
+// addSeatsFields reports the seats configured by meetings maxUsers and the share of these seats in use.
+// Meetings without maxUsers limit are ignored. Utilization is only reported when seats are configured
+func addSeatsFields(ms []Meeting, fields map[string]interface{}) {
+	seats := uint64(0)
+	used := uint64(0)
+	for _, m := range ms {
+		if m.MaxUsers == 0 {
+			continue
+		}
+		seats += m.MaxUsers
+		used += m.ParticipantCount
+	}
+
+	fields["seats_configured"] = seats
+	if seats > 0 {
+		fields["seat_utilization_percent"] = float64(used) / float64(seats) * 100
+	}
+}
+
+// addPendingRecordingAgeFields reports the age of the oldest recording still processing or processed but not
+// published yet, computed from its startTime. It is 0 without pending recordings
+func addPendingRecordingAgeFields(rs []Recording, now time.Time, fields map[string]interface{}) {
+	oldest := uint64(0)
+	for _, r := range rs {
+		if (r.State != "processing" && r.State != "processed") || r.StartTime == 0 {
+			continue
+		}
+
+		start := time.Unix(0, int64(r.StartTime)*int64(time.Millisecond))
+		if age := now.Sub(start); age > 0 && uint64(age/time.Second) > oldest {
+			oldest = uint64(age / time.Second)
+		}
+	}
+
+	fields["oldest_pending_recording_age_seconds"] = oldest
+}
+
+// addRecordingTotalsFields sums the recordings size and length, the longest playback format length of each recording,
+// for storage forecasting. Size is only reported by BigBlueButton 2.3+
+func addRecordingTotalsFields(rs []Recording, fields map[string]interface{}) {
+	size := uint64(0)
+	minutes := uint64(0)
+	for _, r := range rs {
+		size += r.Size
+		minutes += r.Length()
+	}
+
+	fields["recordings_total_bytes"] = size
+	fields["recordings_total_minutes"] = minutes
+}
+
+// addEmptyMeetingsFields counts the meetings without participants, and among them the meetings nobody ever joined,
+// like rooms created by API integrations and never used
+func addEmptyMeetingsFields(ms []Meeting, fields map[string]interface{}) {
+	empty := uint64(0)
+	neverJoined := uint64(0)
+	for _, m := range ms {
+		if m.ParticipantCount == 0 {
+			empty++
+			if !m.HasUserJoined {
+				neverJoined++
+			}
+		}
+	}
+
+	fields["empty_meetings"] = empty
+	fields["never_joined_meetings"] = neverJoined
+}
+
+// addDistinctOriginsFields counts the distinct bbb-origin-server-name values among running meetings, letting
+// multi-frontend deployments check every frontend is creating meetings
+func (b *BigBlueButton) addDistinctOriginsFields(ms []Meeting, fields map[string]interface{}) {
+	key := b.normalizeMetadataKey(originServerMetadata)
+	origins := map[string]struct{}{}
+	for i := range ms {
+		b.parseMetadata(&ms[i].MetadataStruct, ms[i].MeetingID)
+		if origin := ms[i].GetMetadata(key); origin != "" {
+			origins[origin] = struct{}{}
+		}
+	}
+
+	fields["distinct_origins"] = uint64(len(origins))
+}
+
+// addMeetingAgesFields reports the age of the longest running meeting and the sum of meetings ages, computed from
+// their createTime, to detect zombie meetings never ended
+func addMeetingAgesFields(ms []Meeting, now time.Time, fields map[string]interface{}) {
+	longest := uint64(0)
+	total := uint64(0)
+	for _, m := range ms {
+		if m.CreateTime == 0 {
+			continue
+		}
+
+		created := time.Unix(0, int64(m.CreateTime)*int64(time.Millisecond))
+		if age := now.Sub(created); age > 0 {
+			seconds := uint64(age / time.Second)
+			total += seconds
+			if seconds > longest {
+				longest = seconds
+			}
+		}
+	}
+
+	fields["longest_meeting_duration_seconds"] = longest
+	fields["total_meeting_age_seconds"] = total
+}
+
+// addGuestsFields counts the attendees flagged as guest and, when meetings are enriched with getMeetingInfo, the
+// guests waiting in the lobby. Waiting guests are not listed by getMeetings
+func addGuestsFields(ms []Meeting, enriched bool, fields map[string]interface{}) {
+	guests := uint64(0)
+	waiting := uint64(0)
+	for _, m := range ms {
+		for _, a := range m.Attendees {
+			if a.Guest {
+				guests++
+			}
+		}
+		waiting += uint64(len(m.WaitingGuests))
+	}
+
+	fields["guest_participants"] = guests
+	if enriched {
+		fields["waiting_guests"] = waiting
+	}
+}
+
+// addVideoPublishersFields splits video participants into webcam publishers and viewers, attendees without webcam
+// in meetings with at least one publisher. Publishers drive the SFU load
+func addVideoPublishersFields(ms []Meeting, fields map[string]interface{}) {
+	publishers := uint64(0)
+	viewers := uint64(0)
+	for _, m := range ms {
+		meetingPublishers := uint64(0)
+		for _, a := range m.Attendees {
+			if a.HasVideo {
+				meetingPublishers++
+			}
+		}
+		if meetingPublishers > 0 {
+			publishers += meetingPublishers
+			viewers += uint64(len(m.Attendees)) - meetingPublishers
+		}
+	}
+
+	fields["video_publishers"] = publishers
+	fields["video_viewers"] = viewers
+}
+
+// gatherVoiceBridgeRanges emits meetings and voice participants per configured voice bridge range
+func (b *BigBlueButton) gatherVoiceBridgeRanges(acc telegraf.Accumulator, ms []Meeting) {
+	for _, vr := range b.VoiceBridgeRanges {
+		meetings := uint64(0)
+		voiceParticipants := uint64(0)
+		for _, m := range ms {
+			if !vr.Contains(m.VoiceBridge) {
+				continue
+			}
+			meetings++
+			voiceParticipants += m.VoiceParticipantCount
+		}
+
+		fields := map[string]interface{}{
+			"meetings":           meetings,
+			"voice_participants": voiceParticipants,
+		}
+		acc.AddFields("bigbluebutton_voice_bridge", fields, map[string]string{"range": vr.Label})
+	}
+}
+
+// estimatesBandwidth returns true when a bandwidth estimate rate is configured
+func (b *BigBlueButton) estimatesBandwidth() bool {
+	return b.BandwidthAudioKbps > 0 || b.BandwidthVideoKbps > 0
+}
+
+// estimatedBandwidth returns the meeting bandwidth estimate in Mbps, from its participants audio streams
+// and its webcams video streams
+func (b *BigBlueButton) estimatedBandwidth(m Meeting) float64 {
+	kbps := float64(m.ParticipantCount)*b.BandwidthAudioKbps + float64(m.VideoCount)*b.BandwidthVideoKbps
+	return kbps / 1000
+}
+
+// gatherMeetings emits one point per meeting, to find which room is responsible for a participant spike. Configured meeting metadata are added as string fields
+// to carry high cardinality context without creating new series
+func (b *BigBlueButton) gatherMeetings(acc telegraf.Accumulator, ms []Meeting) {
+	for _, m := range ms {
+		fields := map[string]interface{}{
+			"participants":          m.ParticipantCount,
+			"listener_participants": m.ListenerCount,
+			"voice_participants":    m.VoiceParticipantCount,
+			"video_participants":    m.VideoCount,
+			"recording":             boolToUint64(m.Recording),
+		}
+		if b.estimatesBandwidth() {
+			fields["estimated_bandwidth_mbps"] = b.estimatedBandwidth(m)
+		}
+		if len(b.MeetingMetadataFields) > 0 {
+			b.parseMetadata(&m.MetadataStruct, m.MeetingID)
+			for _, md := range b.MeetingMetadataFields {
+				md = b.normalizeMetadataKey(md)
+				if m.ContainsMetadata(md) {
+					fields[md] = m.GetMetadata(md)
+				}
+			}
+		}
+
+		tags := map[string]string{"meeting_id": m.MeetingID}
+		if b.PerMeetingNameTag {
+			tags["name"] = m.MeetingName
+		}
+		acc.AddFields("bigbluebutton_meeting", fields, tags)
+	}
+}
+
+// gatherRecordings emits one point per recording tagged with its state and the configured recording metadata.
+// Finds recordings stuck in processing, which the aggregate recordings count can't show
+func (b *BigBlueButton) gatherRecordings(acc telegraf.Accumulator, rs []Recording) {
+	for _, r := range rs {
+		tags := map[string]string{"record_id": r.RecordID, "state": r.State}
+		if len(b.RecordingMetadataTags) > 0 {
+			b.parseMetadata(&r.MetadataStruct, r.MeetingID)
+			for _, md := range b.RecordingMetadataTags {
+				md = b.normalizeMetadataKey(md)
+				if r.ContainsMetadata(md) {
+					tags[md] = r.GetMetadata(md)
+				}
+			}
+		}
+
+		fields := map[string]interface{}{
+			"published":      boolToUint64(r.Published),
+			"size_bytes":     r.Size,
+			"length_minutes": r.Length(),
+			"participants":   r.Participants,
+		}
+		acc.AddFields("bigbluebutton_recording", fields, tags)
+	}
+}
+
+// wellKnownMessageKeys lists the low cardinality message keys returned by BigBlueButton on empty responses
+var wellKnownMessageKeys = map[string]bool{
+	"noMeetings":   true,
+	"noRecordings": true,
+}
+
+// messageKeyTags returns the well known getMeetings and getRecordings message keys as tags
+func messageKeyTags(m *MeetingsResponse, r *RecordingsResponse) map[string]string {
+	tags := make(map[string]string)
+	if wellKnownMessageKeys[m.MessageKey] {
+		tags["meetings_message_key"] = m.MessageKey
+	}
+
+	if wellKnownMessageKeys[r.MessageKey] {
+		tags["recordings_message_key"] = r.MessageKey
+	}
+
+	return tags
+}
+
+func (b *BigBlueButton) shouldGatheredByMetadata() bool {
+	return len(b.metadataKeys()) > 0
+}
+
+func boolToUint64(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
 }
 
 func toStringMapInterface(in map[string]uint64) map[string]interface{} {
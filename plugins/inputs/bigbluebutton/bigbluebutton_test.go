@@ -1,16 +1,30 @@
 package bigbluebutton
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"encoding/xml"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +32,7 @@ import (
 var emptyState = false
 
 func getXMLResponse(requestURI string) ([]byte, int) {
-	apiName := strings.Split(strings.TrimPrefix(requestURI, "/bigbluebutton/api/"), "?")[0]
+	apiName := strings.Split(strings.TrimPrefix(strings.TrimPrefix(requestURI, "/scalelite/api/"), "/bigbluebutton/api/"), "?")[0]
 	if apiName == "/bigbluebutton/api" {
 		apiName = "healthcheck"
 	}
@@ -43,6 +57,8 @@ func getXMLResponse(requestURI string) ([]byte, int) {
 func getHTTPServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, code := getXMLResponse(r.RequestURI)
+		// suppress the Date header so that clock skew is not reported by default
+		w.Header()["Date"] = nil
 		w.WriteHeader(code)
 		if code == 200 {
 			w.Header().Set("Content-Type", "text/xml")
@@ -72,6 +88,17 @@ func gather(t *testing.T, url string, gatherByMetatdata []string) *testutil.Accu
 	return acc
 }
 
+// withGatherFields adds the fields only emitted on the bigbluebutton measurement
+func withGatherFields(record map[string]uint64) map[string]uint64 {
+	record["parse_errors"] = 0
+	record["rate_limited"] = 0
+	record["auth_ok"] = 1
+	record["version_major"] = 2
+	record["version_minor"] = 0
+	record["version_changed"] = 0
+	return record
+}
+
 func getExpectedEmptyValues() map[string]uint64 {
 	record := map[string]uint64{
 		"meetings":              0,
@@ -79,13 +106,14 @@ func getExpectedEmptyValues() map[string]uint64 {
 		"listener_participants": 0,
 		"voice_participants":    0,
 		"video_participants":    0,
+		"moderators":            0,
 		"active_recordings":     0,
 		"recordings":            0,
 		"published_recordings":  0,
 		"online":                1,
 	}
 
-	return record
+	return withGatherFields(record)
 }
 
 func getExpectedValues() map[string]uint64 {
@@ -95,13 +123,14 @@ func getExpectedValues() map[string]uint64 {
 		"listener_participants": 12,
 		"voice_participants":    4,
 		"video_participants":    1,
+		"moderators":            2,
 		"active_recordings":     1,
 		"recordings":            2,
 		"published_recordings":  1,
 		"online":                1,
 	}
 
-	return record
+	return withGatherFields(record)
 }
 
 func TestBigBlueButton(t *testing.T) {
@@ -111,7 +140,7 @@ func TestBigBlueButton(t *testing.T) {
 
 	acc := gather(t, s.URL, []string{})
 	record := getExpectedValues()
-	tags := make(map[string]string)
+	tags := map[string]string{"version": "2.0"}
 
 	expected := []telegraf.Metric{
 		testutil.MustMetric("bigbluebutton", tags, toStringMapInterface(record), time.Unix(0, 0)),
@@ -129,7 +158,11 @@ func TestBigBlueButtonEmptyState(t *testing.T) {
 
 	acc := gather(t, s.URL, []string{})
 	record := getExpectedEmptyValues()
-	tags := make(map[string]string)
+	tags := map[string]string{
+		"meetings_message_key":   "noMeetings",
+		"recordings_message_key": "noRecordings",
+		"version":                "2.0",
+	}
 
 	expected := []telegraf.Metric{
 		testutil.MustMetric("bigbluebutton", tags, toStringMapInterface(record), time.Unix(0, 0)),
@@ -156,6 +189,7 @@ func TestBigBlueButtonGatherByMetadata(t *testing.T) {
 		"listener_participants": 3,
 		"voice_participants":    3,
 		"video_participants":    1,
+		"moderators":            1,
 		"active_recordings":     0,
 		"recordings":            1,
 		"published_recordings":  1,
@@ -168,10 +202,2524 @@ func TestBigBlueButtonGatherByMetadata(t *testing.T) {
 	}
 
 	expected := []telegraf.Metric{
-		testutil.MustMetric("bigbluebutton", map[string]string{}, toStringMapInterface(record), time.Unix(0, 0)),
+		testutil.MustMetric("bigbluebutton", map[string]string{"version": "2.0"}, toStringMapInterface(record), time.Unix(0, 0)),
 		testutil.MustMetric(metadata, tags, toStringMapInterface(tenantRecord), time.Unix(0, 0)),
 	}
 
 	acc.Wait(len(expected))
 	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
 }
+
+func TestBigBlueButtonMetadataMeasurementTemplate(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.MetadataMeasurementTemplate = "bigbluebutton_{{key}}"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("bigbluebutton_tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+	require.False(t, acc.HasMeasurement("tenant"))
+}
+
+func TestBigBlueButtonGatherByMetadataCombined(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant", "bbb-origin-server-name"})
+	plugin.GatherByMetadataCombined = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	tags := map[string]string{"tenant": "localhost", "bbb-origin-server-name": "greenlight.example.com"}
+	require.True(t, acc.HasPoint("tenant_bbb-origin-server-name", tags, "meetings", uint64(1)))
+	require.True(t, acc.HasPoint("tenant_bbb-origin-server-name", tags, "recordings", uint64(0)))
+	require.False(t, acc.HasMeasurement("tenant"))
+	require.False(t, acc.HasMeasurement("bbb-origin-server-name"))
+}
+
+func TestBigBlueButtonMetadataIncludeExclude(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.MeetingIDMetadata = map[string]string{"tenant": "^([a-z0-9]+)-"}
+	plugin.MetadataExclude = []string{"2432*"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+	for _, m := range acc.Metrics {
+		if m.Measurement == "tenant" {
+			require.Equal(t, "localhost", m.Tags["tenant"])
+		}
+	}
+
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	plugin.MeetingIDMetadata = map[string]string{"tenant": "^([a-z0-9]+)-"}
+	plugin.MetadataInclude = []string{"2432*"}
+	require.NoError(t, plugin.Init())
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "2432dac2"}, "participants", uint64(10)))
+	require.False(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+}
+
+func TestBigBlueButtonMetadataValueRegex(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"bbb-origin-server-name"})
+	plugin.MetadataValueRegex = []MetadataValueRegex{
+		{Key: "bbb-origin-server-name", Pattern: `^greenlight\.`, Replacement: ""},
+	}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("bbb-origin-server-name", map[string]string{"bbb-origin-server-name": "example.com"}, "meetings", uint64(1)))
+
+	plugin = getPlugin(s.URL, []string{"bbb-origin-server-name"})
+	plugin.MetadataValueRegex = []MetadataValueRegex{{Key: "bbb-origin-server-name", Pattern: "("}}
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonTenantMapFile(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"tenants.toml": "[tenant]\nlocalhost = \"Acme\"\n",
+		"tenants.json": `{"tenant": {"localhost": "Acme"}}`,
+	} {
+		file := dir + "/" + name
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+
+		plugin := getPlugin(s.URL, []string{"tenant"})
+		plugin.TenantMapFile = file
+		require.NoError(t, plugin.Init())
+		acc := &testutil.Accumulator{}
+		require.NoError(t, plugin.Gather(acc))
+		require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "Acme"}, "meetings", uint64(1)), name)
+	}
+
+	// metadata_include matches raw values, names are applied to the values kept
+	file := dir + "/tenants.toml"
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.TenantMapFile = file
+	plugin.MetadataInclude = []string{"localhost"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "Acme"}, "meetings", uint64(1)))
+
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	plugin.TenantMapFile = file
+	plugin.MetadataExclude = []string{"Acme"}
+	require.NoError(t, plugin.Init())
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "Acme"}, "meetings", uint64(1)))
+
+	// a mapped empty value does not name meetings without the key
+	require.NoError(t, os.WriteFile(file, []byte("[tenant]\nlocalhost = \"Acme\"\n\"\" = \"Nobody\"\n"), 0644))
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	plugin.TenantMapFile = file
+	require.NoError(t, plugin.Init())
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	for _, m := range acc.Metrics {
+		require.NotEqual(t, "Nobody", m.Tags["tenant"])
+	}
+
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	plugin.TenantMapFile = dir + "/missing.toml"
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonMetadataUnknownValue(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.MetadataUnknownValue = "unknown"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "participants", uint64(5)))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "unknown"}, "participants", uint64(10)))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "unknown"}, "recordings", uint64(1)))
+}
+
+func TestBigBlueButtonMetadataKeyNormalization(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"BBB-Origin-Server-Name", "origin-server-name"})
+	plugin.MetadataKeyStripPrefix = "bbb-"
+	plugin.MetadataKeyLowercase = true
+	plugin.MetadataKeySanitize = true
+	require.NoError(t, plugin.Init())
+	require.Equal(t, []string{"origin-server-name"}, plugin.metadataKeys())
+	require.Equal(t, "tenant_name", plugin.normalizeMetadataKey("Tenant Name"))
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("origin-server-name", map[string]string{"origin-server-name": "greenlight.example.com"}, "meetings", uint64(1)))
+}
+
+func TestBigBlueButtonKnownMetadataValues(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.KnownMetadataValues = map[string][]string{"tenant": {"localhost", "acme"}}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "acme"}, "meetings", uint64(0)))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "acme"}, "participants", uint64(0)))
+
+	// known values keys are normalized like the gathered metadata keys
+	plugin = getPlugin(s.URL, []string{"Tenant"})
+	plugin.MetadataKeyLowercase = true
+	plugin.KnownMetadataValues = map[string][]string{"Tenant": {"acme"}}
+	require.NoError(t, plugin.Init())
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "acme"}, "meetings", uint64(0)))
+}
+
+func TestBigBlueButtonMeetingIDMetadata(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.MeetingIDMetadata = map[string]string{"tenant": "^([a-z0-9]+)-"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "2432dac2"}, "participants", uint64(10)))
+
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	plugin.MeetingIDMetadata = map[string]string{"tenant": "^[a-z0-9]+-"}
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonGroupByMetadataWorkers(t *testing.T) {
+	mr := &MeetingsResponse{}
+	for i := 0; i < 300; i++ {
+		m := Meeting{ParticipantCount: uint64(i)}
+		m.Metadata.Inner = []byte(fmt.Sprintf("<tenant>tenant-%d</tenant>", i%100))
+		mr.Meetings.Values = append(mr.Meetings.Values, m)
+	}
+	rr := &RecordingsResponse{}
+	hr := &HealthCheck{ReturnCode: "SUCCESS"}
+
+	plugin := getPlugin("http://localhost", []string{"tenant"})
+	plugin.MetadataWorkers = 1
+	expected := plugin.GetMetadataRecords(mr, rr, hr)
+	require.Len(t, expected["tenant"], 100)
+	require.Equal(t, uint64(3), expected["tenant"]["tenant-1"].Meetings)
+	require.Equal(t, uint64(1+101+201), expected["tenant"]["tenant-1"].Participants)
+
+	plugin.MetadataWorkers = 8
+	require.Equal(t, expected, plugin.GetMetadataRecords(mr, rr, hr))
+}
+
+func TestBigBlueButtonDeprecatedOptions(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	// configurations setting servers.balancer_state still load, the static state is no longer reported
+	plugin := BigBlueButton{
+		SecretKey: "OxShRR1sT8FrJZq",
+		Servers:   []Server{{Name: "bbb1", URL: s.URL, BalancerState: "cordoned"}},
+		Log:       testutil.Logger{},
+	}
+	require.True(t, deprecatedOptions[0].isSet(&plugin))
+	require.NoError(t, plugin.Init())
+	require.Empty(t, plugin.Servers[0].BalancerState)
+	require.False(t, deprecatedOptions[0].isSet(&plugin))
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	m, ok := acc.Get("bigbluebutton")
+	require.True(t, ok)
+	require.NotContains(t, m.Tags, "balancer_state")
+}
+
+func TestBigBlueButtonAudit(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant", "unknown"})
+	plugin.ExpectedVersion = "2.0"
+	require.NoError(t, plugin.Init())
+
+	var report bytes.Buffer
+	require.NoError(t, plugin.Audit(&report))
+	require.Contains(t, report.String(), "[OK]   server reachable, BigBlueButton version 2.0")
+	require.Contains(t, report.String(), "[OK]   metadata tenant found with 1 value(s)")
+	require.Contains(t, report.String(), "[WARN] metadata unknown not found")
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.ExpectedVersion = "2.7"
+	require.NoError(t, plugin.Init())
+	report.Reset()
+	require.Error(t, plugin.Audit(&report))
+	require.Contains(t, report.String(), "[FAIL] version 2.0 does not match expected_version 2.7")
+}
+
+func TestBigBlueButtonRequireHTTPS(t *testing.T) {
+	plugin := getPlugin("http://bbb.example.com", []string{})
+	plugin.RequireHTTPS = true
+	require.Error(t, plugin.Init())
+
+	plugin = getPlugin("https://bbb.example.com", []string{})
+	plugin.RequireHTTPS = true
+	require.NoError(t, plugin.Init())
+
+	plugin = getPlugin("http://127.0.0.1:8090", []string{})
+	plugin.RequireHTTPS = true
+	require.NoError(t, plugin.Init())
+}
+
+func TestBigBlueButtonIPv6URL(t *testing.T) {
+	plugin := getPlugin("https://[2001:db8::1]:8443/", []string{})
+	require.NoError(t, plugin.Init())
+	require.True(t, strings.HasPrefix(plugin.endpoints.Load().getMeetings, "https://[2001:db8::1]:8443/bigbluebutton/api/getMeetings?checksum="))
+	require.Equal(t, "https://[2001:db8::1]:8443/bigbluebutton/api", plugin.endpoints.Load().healthCheck)
+
+	plugin = getPlugin("https://2001:db8::1", []string{})
+	require.Error(t, plugin.Init())
+
+	plugin = getPlugin("bbb.example.com", []string{})
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonVersionChanged(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+
+	fields := map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.6"}, fields)
+	require.Equal(t, uint64(0), fields["version_changed"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.6"}, fields)
+	require.Equal(t, uint64(0), fields["version_changed"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.7"}, fields)
+	require.Equal(t, uint64(1), fields["version_changed"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.7"}, fields)
+	require.Equal(t, uint64(0), fields["version_changed"])
+}
+
+func TestParseVersion(t *testing.T) {
+	major, minor, ok := parseVersion("2.7.3")
+	require.True(t, ok)
+	require.Equal(t, uint64(2), major)
+	require.Equal(t, uint64(7), minor)
+
+	major, minor, ok = parseVersion("3.0-beta")
+	require.True(t, ok)
+	require.Equal(t, uint64(3), major)
+	require.Equal(t, uint64(0), minor)
+
+	_, _, ok = parseVersion("unknown")
+	require.False(t, ok)
+}
+
+func TestBigBlueButtonVersionMismatch(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+	plugin.ExpectedVersion = "2.7"
+
+	fields := map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.7"}, fields)
+	require.Equal(t, uint64(0), fields["version_mismatch"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.6"}, fields)
+	require.Equal(t, uint64(1), fields["version_mismatch"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.0", BBBVersion: "2.7.3"}, fields)
+	require.Equal(t, uint64(0), fields["version_mismatch"])
+	require.Equal(t, uint64(7), fields["version_minor"])
+
+	fields = map[string]interface{}{}
+	plugin.addVersionFields(&HealthCheck{Version: "2.0", BBBVersion: "2.70.1"}, fields)
+	require.Equal(t, uint64(1), fields["version_mismatch"])
+}
+
+func TestBigBlueButtonClockSkew(t *testing.T) {
+	emptyState = false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	acc := gather(t, s.URL, []string{})
+	skew, ok := acc.Int64Field("bigbluebutton", "clock_skew_seconds")
+	require.True(t, ok)
+	require.InDelta(t, 3600, skew, 2)
+}
+
+func TestBigBlueButtonRecordingsByState(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.RecordingsByState = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	expected := map[string]uint64{
+		"processing":  0,
+		"processed":   0,
+		"published":   1,
+		"unpublished": 1,
+		"deleted":     0,
+	}
+
+	for state, count := range expected {
+		acc.AssertContainsTaggedFields(t, "bigbluebutton_recordings", map[string]interface{}{"recordings": count}, map[string]string{"state": state})
+	}
+}
+
+func TestBigBlueButtonPathPrefixes(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.PathPrefixes = []string{"/unknown", "/bigbluebutton"}
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "/bigbluebutton", plugin.PathPrefix)
+	require.Equal(t, fmt.Sprintf("%s/bigbluebutton/api", s.URL), plugin.endpoints.Load().healthCheck)
+}
+
+func TestBigBlueButtonVoiceBridgeRanges(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.VoiceBridgeRanges = []VoiceBridgeRange{
+		{Label: "trunk-a", From: 70000, To: 74999},
+		{Label: "trunk-b", From: 75000, To: 79999},
+		{Label: "trunk-c", From: 80000, To: 89999},
+	}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_voice_bridge", map[string]interface{}{"meetings": uint64(1), "voice_participants": uint64(1)}, map[string]string{"range": "trunk-a"})
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_voice_bridge", map[string]interface{}{"meetings": uint64(1), "voice_participants": uint64(3)}, map[string]string{"range": "trunk-b"})
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_voice_bridge", map[string]interface{}{"meetings": uint64(0), "voice_participants": uint64(0)}, map[string]string{"range": "trunk-c"})
+}
+
+func TestBigBlueButtonClientTypes(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherClientTypes = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	count, ok := acc.Uint64Field("bigbluebutton", "html5_participants")
+	require.True(t, ok)
+	require.Equal(t, uint64(15), count)
+}
+
+func TestBigBlueButtonAttendeeRoles(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.GatherAttendeeRoles = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	viewers, ok := acc.Uint64Field("bigbluebutton", "viewer_participants")
+	require.True(t, ok)
+	require.Equal(t, uint64(13), viewers)
+	moderators, ok := acc.Uint64Field("bigbluebutton", "moderator_participants")
+	require.True(t, ok)
+	require.Equal(t, uint64(2), moderators)
+
+	require.True(t, acc.HasUIntField("tenant", "viewer_participants"))
+	require.True(t, acc.HasUIntField("tenant", "moderator_participants"))
+}
+
+func TestBigBlueButtonPlaybackFormats(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherPlaybackFormats = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	for field, expected := range map[string]uint64{
+		"presentation_format_recordings": 2,
+		"podcast_format_recordings":      2,
+		"video_format_recordings":        0,
+		"screenshare_format_recordings":  0,
+		"notes_format_recordings":        0,
+	} {
+		count, ok := acc.Uint64Field("bigbluebutton", field)
+		require.True(t, ok, field)
+		require.Equal(t, expected, count, field)
+	}
+}
+
+func TestBigBlueButtonDebugMemoryStats(t *testing.T) {
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, nil)
+	plugin.DebugMemoryStats = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	allocated, ok := acc.Uint64Field("bigbluebutton_debug", "gather_alloc_bytes")
+	require.True(t, ok)
+	require.NotZero(t, allocated)
+	require.True(t, acc.HasUIntField("bigbluebutton_debug", "gather_mallocs"))
+	require.True(t, acc.HasUIntField("bigbluebutton_debug", "heap_inuse_peak_bytes"))
+}
+
+func TestBigBlueButtonRecordingTotals(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherRecordingTotals = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	size, ok := acc.Uint64Field("bigbluebutton", "recordings_total_bytes")
+	require.True(t, ok)
+	require.Equal(t, uint64(2048), size)
+	minutes, ok := acc.Uint64Field("bigbluebutton", "recordings_total_minutes")
+	require.True(t, ok)
+	require.Equal(t, uint64(33), minutes)
+}
+
+func TestBigBlueButtonSelfStats(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	requests := plugin.stats.apiRequests.Get()
+	meetings := plugin.stats.meetingsParsed.Get()
+	recordings := plugin.stats.recordingsParsed.Get()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.Equal(t, requests+3, plugin.stats.apiRequests.Get())
+	require.Equal(t, meetings+2, plugin.stats.meetingsParsed.Get())
+	require.Equal(t, recordings+2, plugin.stats.recordingsParsed.Get())
+	require.NotZero(t, plugin.stats.gatherTime.Get())
+}
+
+// debugLogger records debug messages
+type debugLogger struct {
+	testutil.Logger
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *debugLogger) Debugf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestBigBlueButtonDebugLogging(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	log := &debugLogger{}
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Log = log
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	logged := strings.Join(log.lines, "\n")
+	require.Contains(t, logged, "/bigbluebutton/api/getMeetings?checksum=REDACTED: status 200 after")
+	require.Regexp(t, `getRecordings\?checksum=REDACTED: read \d+ bytes in`, logged)
+	require.NotRegexp(t, `checksum=[0-9a-f]`, logged)
+}
+
+func TestRedactChecksum(t *testing.T) {
+	require.Equal(t, "http://bbb/api/getRecordings?state=any&checksum=REDACTED", redactChecksum("http://bbb/api/getRecordings?state=any&checksum=4f2a"))
+	require.Equal(t, "http://bbb/api/getMeetings?checksum=REDACTED&x=1", redactChecksum("http://bbb/api/getMeetings?checksum=4f2a&x=1"))
+}
+
+func TestBigBlueButtonSecretKeyFile(t *testing.T) {
+	failing := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	file, err := ioutil.TempFile("", "bbb_secret")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	require.NoError(t, ioutil.WriteFile(file.Name(), []byte("first-secret\n"), 0600))
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.SecretKey = ""
+	plugin.SecretKeyFile = file.Name()
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "first-secret", plugin.SecretKey)
+	getMeetings := plugin.endpoints.Load().getMeetings
+
+	require.NoError(t, ioutil.WriteFile(file.Name(), []byte("second-secret"), 0600))
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Equal(t, "first-secret", plugin.SecretKey)
+
+	failing = true
+	require.Error(t, plugin.Gather(acc))
+	require.Equal(t, "second-secret", plugin.SecretKey)
+	require.NotEqual(t, getMeetings, plugin.endpoints.Load().getMeetings)
+
+	plugin.SecretKeyFile = file.Name() + ".missing"
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonSecretReferences(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	file, err := ioutil.TempFile("", "bbb_password")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	require.NoError(t, ioutil.WriteFile(file.Name(), []byte("file-password\n"), 0600))
+	os.Setenv("BBB_TEST_SECRET", "env-secret")
+	defer os.Unsetenv("BBB_TEST_SECRET")
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.SecretKey = "@env:BBB_TEST_SECRET"
+	plugin.Username = "plain-user"
+	plugin.Password = "@file:" + file.Name()
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "env-secret", plugin.SecretKey)
+	require.Equal(t, "plain-user", plugin.Username)
+	require.Equal(t, "file-password", plugin.Password)
+
+	var report bytes.Buffer
+	require.NoError(t, plugin.Audit(&report))
+	require.NotContains(t, report.String(), "env-secret")
+	require.NotContains(t, report.String(), "file-password")
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.SecretKey = "@env:BBB_TEST_MISSING"
+	require.EqualError(t, plugin.Init(), "secret_key: environment variable BBB_TEST_MISSING is not set")
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.SecretKeys = []string{"@file:" + file.Name() + ".missing"}
+	require.Error(t, plugin.Init())
+}
+
+func TestRecordingTracker(t *testing.T) {
+	now := time.Now()
+	tracker := newRecordingTracker(time.Hour)
+
+	published, failed := tracker.Update([]Recording{{RecordID: "a", State: "processing"}, {RecordID: "b", State: "processed"}}, now)
+	require.Equal(t, uint64(0), published)
+	require.Equal(t, uint64(0), failed)
+
+	published, failed = tracker.Update([]Recording{{RecordID: "a", State: "published"}}, now.Add(time.Minute))
+	require.Equal(t, uint64(1), published)
+	require.Equal(t, uint64(0), failed)
+
+	published, failed = tracker.Update([]Recording{{RecordID: "a", State: "published"}}, now.Add(2*time.Hour))
+	require.Equal(t, uint64(0), published)
+	require.Equal(t, uint64(1), failed)
+}
+
+func TestBigBlueButtonPerMeetingMetadataFields(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.PerMeetingMetrics = true
+	plugin.MeetingMetadataFields = []string{"bbb-origin-server-name"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	fields := map[string]interface{}{
+		"participants":           uint64(5),
+		"listener_participants":  uint64(3),
+		"voice_participants":     uint64(3),
+		"video_participants":     uint64(1),
+		"recording":              uint64(0),
+		"bbb-origin-server-name": "greenlight.example.com",
+	}
+	tags := map[string]string{"meeting_id": "b0a78452-2266-4a0a-abae-8a016db8fccd"}
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_meeting", fields, tags)
+}
+
+func TestBigBlueButtonPerMeetingNameTag(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.PerMeetingMetrics = true
+	plugin.PerMeetingNameTag = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	fields := map[string]interface{}{
+		"participants":          uint64(10),
+		"listener_participants": uint64(9),
+		"voice_participants":    uint64(1),
+		"video_participants":    uint64(0),
+		"recording":             uint64(1),
+	}
+	tags := map[string]string{"meeting_id": "2432dac2-ded4-4f77-9f58-ba6610df1890", "name": "Meeting 2"}
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_meeting", fields, tags)
+}
+
+func TestBigBlueButtonPerRecordingMetadataTags(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.PerRecordingMetrics = true
+	plugin.RecordingMetadataTags = []string{"tenant"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	tags := map[string]string{
+		"record_id": "ffbfc4cc24428694e8b53a4e144f414052431693-1530718721124",
+		"state":     "published",
+		"tenant":    "localhost",
+	}
+	fields := map[string]interface{}{
+		"published":      uint64(1),
+		"size_bytes":     uint64(2048),
+		"length_minutes": uint64(0),
+		"participants":   uint64(3),
+	}
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_recording", fields, tags)
+
+	tags = map[string]string{
+		"record_id": "ffbfc4cc24428694e8b53a4e144f414052431693-1530278898111",
+		"state":     "unpublished",
+	}
+	fields = map[string]interface{}{
+		"published":      uint64(0),
+		"size_bytes":     uint64(0),
+		"length_minutes": uint64(33),
+		"participants":   uint64(7),
+	}
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_recording", fields, tags)
+}
+
+func TestBigBlueButtonTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Timeout = config.Duration(50 * time.Millisecond)
+	require.NoError(t, plugin.Init())
+
+	start := time.Now()
+	acc := &testutil.Accumulator{}
+	require.Error(t, plugin.Gather(acc))
+	require.Less(t, int64(time.Since(start)), int64(200*time.Millisecond))
+}
+
+func TestBigBlueButtonConcurrentFetch(t *testing.T) {
+	emptyState = false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+
+	start := time.Now()
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Less(t, int64(time.Since(start)), int64(250*time.Millisecond))
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()))
+}
+
+func TestBigBlueButtonRecordingsCacheInterval(t *testing.T) {
+	emptyState = false
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.RequestURI, "getRecordings") {
+			calls++
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.RecordingsCacheInterval = config.Duration(time.Minute)
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < 3; i++ {
+		acc := &testutil.Accumulator{}
+		require.NoError(t, plugin.Gather(acc))
+		acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()))
+	}
+	require.Equal(t, 1, calls)
+
+	plugin.recordingsFetchedAt = time.Now().Add(-time.Minute)
+	require.NoError(t, plugin.Gather(&testutil.Accumulator{}))
+	require.Equal(t, 2, calls)
+}
+
+func TestBigBlueButtonRecordingsFailureAfterSuccess(t *testing.T) {
+	emptyState = false
+	failing := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if failing && strings.Contains(r.RequestURI, "getRecordings") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Gather(&testutil.Accumulator{}))
+
+	failing = true
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+
+	record := getExpectedValues()
+	delete(record, "recordings")
+	delete(record, "published_recordings")
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+
+	// with a valid cache interval, the previous recordings are still reported
+	plugin.RecordingsCacheInterval = config.Duration(time.Minute)
+	plugin.recordingsFetchedAt = time.Now().Add(-time.Minute)
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	record = getExpectedValues()
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonResponseTimes(t *testing.T) {
+	emptyState = false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherResponseTimes = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	for _, field := range []string{"meetings_response_time_ms", "recordings_response_time_ms", "healthcheck_response_time_ms"} {
+		v, ok := acc.FloatField("bigbluebutton", field)
+		require.True(t, ok, field)
+		require.GreaterOrEqual(t, v, 10.0, field)
+	}
+}
+
+func TestBigBlueButtonPartialGather(t *testing.T) {
+	emptyState = false
+	failing := ""
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing != "" && strings.HasSuffix(r.URL.Path, failing) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	failing = "getRecordings"
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+
+	record := getExpectedValues()
+	delete(record, "recordings")
+	delete(record, "published_recordings")
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+
+	failing = "/bigbluebutton/api"
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Len(t, acc.Errors, 1)
+
+	record = getExpectedValues()
+	record["online"] = 0
+	delete(record, "version_major")
+	delete(record, "version_minor")
+	delete(record, "version_changed")
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonResolvedIPTag(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.ResolvedIPTag = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()), map[string]string{"resolved_ip": "127.0.0.1", "version": "2.0"})
+}
+
+func TestBigBlueButtonPrecision(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Precision = config.Duration(time.Second)
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, 0, m.Time().Nanosecond())
+	}
+}
+
+func TestBigBlueButtonReturnCodeTag(t *testing.T) {
+	emptyState = false
+	failing := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing && strings.HasSuffix(r.URL.Path, "getRecordings") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.ReturnCodeTag = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()), map[string]string{"returncode": "SUCCESS", "version": "2.0"})
+
+	failing = true
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("bigbluebutton", map[string]string{"returncode": "FAILED", "version": "2.0"}, "meetings", uint64(2)))
+}
+
+func TestBigBlueButtonRateLimited(t *testing.T) {
+	emptyState = false
+	throttled := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !throttled && strings.Contains(r.RequestURI, "getRecordings") {
+			throttled = true
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	record := getExpectedValues()
+	record["rate_limited"] = 1
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(acc))
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()))
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 2, 12, 15, 4, 0, 0, time.UTC)
+
+	delay, ok := retryAfter(http.Header{"Retry-After": {"3"}}, now)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, delay)
+
+	delay, ok = retryAfter(http.Header{"Retry-After": {"Fri, 12 Feb 2021 15:04:05 GMT"}}, now)
+	require.True(t, ok)
+	require.Equal(t, 5*time.Second, delay)
+
+	_, ok = retryAfter(http.Header{}, now)
+	require.False(t, ok)
+}
+
+func TestBigBlueButtonMeetingPolicies(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherMeetingPolicies = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	record := getExpectedValues()
+	record["duration_capped_meetings"] = 1
+	record["auto_start_recording_meetings"] = 1
+	record["allow_start_stop_recording_meetings"] = 0
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestAddMeetingFeatureField(t *testing.T) {
+	enabled := true
+	disabled := false
+	ms := []Meeting{{MuteOnStart: &enabled}, {MuteOnStart: &disabled}, {MuteOnStart: &enabled, WebcamsOnlyForModerator: &disabled}, {}}
+	fields := map[string]interface{}{}
+	addMeetingPoliciesFields(ms, fields)
+	require.Equal(t, uint64(2), fields["mute_on_start_meetings"])
+	require.Equal(t, uint64(0), fields["webcams_only_for_moderator_meetings"])
+
+	fields = map[string]interface{}{}
+	addMeetingPoliciesFields([]Meeting{{}}, fields)
+	require.NotContains(t, fields, "mute_on_start_meetings")
+	require.NotContains(t, fields, "webcams_only_for_moderator_meetings")
+}
+
+func TestRecordingAgeCounts(t *testing.T) {
+	now := time.Date(2021, 2, 12, 15, 4, 0, 0, time.UTC)
+	millis := func(t time.Time) uint64 { return uint64(t.UnixNano() / int64(time.Millisecond)) }
+	rs := []Recording{
+		{StartTime: millis(time.Date(2021, 2, 1, 8, 0, 0, 0, time.UTC))},
+		{StartTime: millis(time.Date(2021, 1, 31, 8, 0, 0, 0, time.UTC))},
+		{StartTime: millis(time.Date(2020, 9, 1, 8, 0, 0, 0, time.UTC))},
+		{StartTime: millis(time.Date(2020, 8, 1, 8, 0, 0, 0, time.UTC))},
+		{},
+	}
+
+	expected := map[string]uint64{
+		"this_month":    1,
+		"1_6_months":    2,
+		"over_6_months": 1,
+	}
+	require.Equal(t, expected, RecordingAgeCounts(rs, now))
+}
+
+func TestBigBlueButtonAudioStates(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherAudioStates = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	record := getExpectedValues()
+	record["voice_connected_attendees"] = 3
+	record["listen_only_attendees"] = 11
+	record["audio_pending_attendees"] = 1
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestCounterTracker(t *testing.T) {
+	tracker := counterTracker{}
+	now := time.Now()
+	minutes, starts := tracker.Update([]Meeting{{InternalMeetingID: "a", ParticipantCount: 4}}, now)
+	require.Equal(t, float64(0), minutes)
+	require.Equal(t, uint64(0), starts)
+
+	minutes, starts = tracker.Update([]Meeting{{InternalMeetingID: "a", ParticipantCount: 4}, {InternalMeetingID: "b", ParticipantCount: 2}}, now.Add(time.Minute))
+	require.Equal(t, float64(6), minutes)
+	require.Equal(t, uint64(1), starts)
+
+	minutes, starts = tracker.Update([]Meeting{{InternalMeetingID: "b", ParticipantCount: 3}}, now.Add(3*time.Minute))
+	require.Equal(t, float64(12), minutes)
+	require.Equal(t, uint64(1), starts)
+}
+
+func TestEventTracker(t *testing.T) {
+	tracker := eventTracker{}
+	require.Empty(t, tracker.Update(true, 3))
+	require.Empty(t, tracker.Update(true, 2))
+
+	events := tracker.Update(true, 0)
+	require.Contains(t, events, "meetings_dropped_to_zero")
+	require.Len(t, events, 1)
+
+	tracker.Update(true, 4)
+	events = tracker.Update(false, 0)
+	require.Contains(t, events, "server_offline")
+	require.Len(t, events, 1)
+	require.Empty(t, tracker.Update(false, 0))
+
+	events = tracker.Update(true, 4)
+	require.Contains(t, events, "server_online")
+	require.Len(t, events, 1)
+}
+
+func TestBigBlueButtonGuestPolicies(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherGuestPolicies = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("bigbluebutton_guest_policy", map[string]string{"policy": "ALWAYS_ACCEPT"}, "meetings", uint64(1)))
+	require.True(t, acc.HasPoint("bigbluebutton_guest_policy", map[string]string{"policy": "ASK_MODERATOR"}, "meetings", uint64(0)))
+	require.True(t, acc.HasPoint("bigbluebutton_guest_policy", map[string]string{"policy": "unknown"}, "meetings", uint64(1)))
+}
+
+func TestBigBlueButtonChecksumError(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<response><returncode>FAILED</returncode><messageKey>checksumError</messageKey></response>"))
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	err := plugin.Gather(acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum rejected")
+
+	acc.AssertContainsFields(t, "bigbluebutton", map[string]interface{}{"auth_ok": uint64(0), "online": uint64(0), "api_online": uint64(0)})
+}
+
+func TestBigBlueButtonFieldsAllowlist(t *testing.T) {
+	emptyState = false
+	var calls []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		body, code := getXMLResponse(r.RequestURI)
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Fields = []string{"meetings", "participants"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.Equal(t, []string{"/bigbluebutton/api/getMeetings"}, calls)
+	acc.AssertContainsTaggedFields(t, "bigbluebutton", map[string]interface{}{
+		"meetings":     uint64(2),
+		"participants": uint64(15),
+	}, map[string]string{})
+}
+
+func TestBigBlueButtonWebManagement(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	actuator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/actuator/metrics/jvm.memory.used":
+			require.Equal(t, "area:heap", r.URL.Query().Get("tag"))
+			w.Write([]byte(`{"name":"jvm.memory.used","measurements":[{"statistic":"VALUE","value":1024}]}`))
+		case "/actuator/metrics/jvm.threads.live":
+			w.Write([]byte(`{"name":"jvm.threads.live","measurements":[{"statistic":"VALUE","value":42}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer actuator.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.WebManagementURL = actuator.URL + "/actuator/"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	acc.AssertContainsFields(t, "bigbluebutton_web", map[string]interface{}{
+		"heap_used_bytes": float64(1024),
+		"threads_live":    float64(42),
+	})
+}
+
+func TestBigBlueButtonSchemaVersionTag(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.SchemaVersionTag = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.NotEmpty(t, acc.Metrics)
+	for _, m := range acc.Metrics {
+		require.Equal(t, schemaVersion, m.Tags["schema_version"], m.Measurement)
+	}
+}
+
+func TestBigBlueButtonTCPCheck(t *testing.T) {
+	emptyState = false
+	online := getHTTPServer()
+	defer online.Close()
+
+	plugin := getPlugin(online.URL, []string{})
+	plugin.TCPCheck = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	record := getExpectedValues()
+	record["tcp_reachable"] = 1
+	record["api_online"] = 1
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.TCPCheck = true
+	require.NoError(t, plugin.Init())
+	acc = &testutil.Accumulator{}
+	require.Error(t, plugin.Gather(acc))
+	acc.AssertContainsFields(t, "bigbluebutton", map[string]interface{}{
+		"tcp_reachable": uint64(1),
+		"api_online":    uint64(0),
+		"online":        uint64(0),
+	})
+
+	// without tcp_check, the server is still reported offline
+	withoutTCPCheck := getPlugin(s.URL, []string{})
+	require.NoError(t, withoutTCPCheck.Init())
+	acc = &testutil.Accumulator{}
+	require.Error(t, withoutTCPCheck.Gather(acc))
+	acc.AssertContainsFields(t, "bigbluebutton", map[string]interface{}{
+		"api_online": uint64(0),
+		"online":     uint64(0),
+	})
+
+	s.Close()
+	acc = &testutil.Accumulator{}
+	require.Error(t, plugin.Gather(acc))
+	acc.AssertContainsFields(t, "bigbluebutton", map[string]interface{}{
+		"tcp_reachable": uint64(0),
+		"api_online":    uint64(0),
+		"online":        uint64(0),
+	})
+}
+
+func TestBigBlueButtonEstimatedBandwidth(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.BandwidthAudioKbps = 40
+	plugin.BandwidthVideoKbps = 500
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	// 15 participants x 40 kbps + 1 webcam x 500 kbps
+	bandwidth, ok := acc.FloatField("bigbluebutton", "estimated_bandwidth_mbps")
+	require.True(t, ok)
+	require.InDelta(t, 1.1, bandwidth, 1e-9)
+}
+
+func TestAddMeetingAgesFields(t *testing.T) {
+	millis := func(t time.Time) uint64 { return uint64(t.UnixNano() / int64(time.Millisecond)) }
+	now := time.Date(2021, 2, 12, 15, 0, 0, 0, time.UTC)
+	ms := []Meeting{
+		{CreateTime: millis(now.Add(-2 * time.Hour))},
+		{CreateTime: millis(now.Add(-3 * 24 * time.Hour))},
+		{},
+	}
+	fields := map[string]interface{}{}
+	addMeetingAgesFields(ms, now, fields)
+	require.Equal(t, uint64(3*24*3600), fields["longest_meeting_duration_seconds"])
+	require.Equal(t, uint64(3*24*3600+2*3600), fields["total_meeting_age_seconds"])
+}
+
+func TestAddPendingRecordingAgeFields(t *testing.T) {
+	millis := func(t time.Time) uint64 { return uint64(t.UnixNano() / int64(time.Millisecond)) }
+	now := time.Date(2021, 2, 12, 15, 0, 0, 0, time.UTC)
+	rs := []Recording{
+		{State: "processing", StartTime: millis(now.Add(-2 * time.Hour))},
+		{State: "processed", StartTime: millis(now.Add(-5 * time.Hour))},
+		{State: "published", StartTime: millis(now.Add(-48 * time.Hour))},
+		{State: "unpublished", StartTime: millis(now.Add(-72 * time.Hour))},
+	}
+	fields := map[string]interface{}{}
+	addPendingRecordingAgeFields(rs, now, fields)
+	require.Equal(t, uint64(5*3600), fields["oldest_pending_recording_age_seconds"])
+
+	fields = map[string]interface{}{}
+	addPendingRecordingAgeFields(rs[2:], now, fields)
+	require.Equal(t, uint64(0), fields["oldest_pending_recording_age_seconds"])
+}
+
+func TestGroupBreakouts(t *testing.T) {
+	var breakout Meeting
+	require.NoError(t, xml.Unmarshal([]byte(`<meeting><meetingID>b1</meetingID><internalMeetingID>b1-internal</internalMeetingID>
+		<participantCount>3</participantCount><isBreakout>true</isBreakout>
+		<breakout><parentMeetingID>p-internal</parentMeetingID><sequence>1</sequence></breakout></meeting>`), &breakout))
+	require.True(t, breakout.IsBreakout)
+	require.Equal(t, "p-internal", breakout.ParentMeetingID)
+
+	ms := []Meeting{
+		breakout,
+		{MeetingID: "p", InternalMeetingID: "p-internal", ParticipantCount: 2, Attendees: []Attendee{{UserID: "a"}}},
+		{MeetingID: "b2", IsBreakout: true, ParentMeetingID: "p-internal", ParticipantCount: 4, Attendees: []Attendee{{UserID: "b"}}},
+		{MeetingID: "orphan", IsBreakout: true, ParentMeetingID: "gone", ParticipantCount: 1},
+	}
+	grouped := groupBreakouts(ms)
+	require.Len(t, grouped, 2)
+	require.Equal(t, "p", grouped[0].MeetingID)
+	require.Equal(t, uint64(9), grouped[0].ParticipantCount)
+	require.Len(t, grouped[0].Attendees, 2)
+	require.Equal(t, "orphan", grouped[1].MeetingID)
+	require.Len(t, ms[1].Attendees, 1)
+}
+
+func TestAddEmptyMeetingsFields(t *testing.T) {
+	ms := []Meeting{
+		{ParticipantCount: 3, HasUserJoined: true},
+		{HasUserJoined: true},
+		{},
+		{},
+	}
+	fields := map[string]interface{}{}
+	addEmptyMeetingsFields(ms, fields)
+	require.Equal(t, uint64(3), fields["empty_meetings"])
+	require.Equal(t, uint64(2), fields["never_joined_meetings"])
+}
+
+func TestAddSeatsFields(t *testing.T) {
+	fields := map[string]interface{}{}
+	addSeatsFields([]Meeting{{ParticipantCount: 10}}, fields)
+	require.Equal(t, uint64(0), fields["seats_configured"])
+	require.NotContains(t, fields, "seat_utilization_percent")
+
+	fields = map[string]interface{}{}
+	addSeatsFields([]Meeting{{ParticipantCount: 10, MaxUsers: 20}, {ParticipantCount: 5, MaxUsers: 20}, {ParticipantCount: 7}}, fields)
+	require.Equal(t, uint64(40), fields["seats_configured"])
+	require.Equal(t, 37.5, fields["seat_utilization_percent"])
+}
+
+func TestBigBlueButtonDiscardUnusedMetadata(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, nil)
+	require.NoError(t, plugin.Init())
+	m, err := plugin.getMeetings()
+	require.NoError(t, err)
+	for _, meeting := range m.Meetings.Values {
+		require.Nil(t, meeting.Metadata.Inner)
+	}
+
+	plugin = getPlugin(s.URL, []string{"tenant"})
+	require.NoError(t, plugin.Init())
+	m, err = plugin.getMeetings()
+	require.NoError(t, err)
+	require.NotNil(t, m.Meetings.Values[0].Metadata.Inner)
+}
+
+func TestAddDistinctOriginsFields(t *testing.T) {
+	origin := func(name string) Meeting {
+		var m Meeting
+		m.Metadata.Inner = []byte("<bbb-origin-server-name>" + name + "</bbb-origin-server-name>")
+		return m
+	}
+	ms := []Meeting{origin("a.example.com"), origin("b.example.com"), origin("a.example.com"), {}}
+	fields := map[string]interface{}{}
+	(&BigBlueButton{}).addDistinctOriginsFields(ms, fields)
+	require.Equal(t, uint64(2), fields["distinct_origins"])
+}
+
+func TestBigBlueButtonGatherDistinctOrigins(t *testing.T) {
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, nil)
+	plugin.GatherDistinctOrigins = true
+	require.NoError(t, plugin.Init())
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.True(t, acc.HasUIntField("bigbluebutton", "distinct_origins"))
+	v, _ := acc.Get("bigbluebutton")
+	require.Equal(t, uint64(1), v.Fields["distinct_origins"])
+}
+
+func TestAddGuestsFields(t *testing.T) {
+	ms := []Meeting{
+		{Attendees: []Attendee{{Guest: true}, {}}},
+		{Attendees: []Attendee{{Guest: true}}},
+	}
+	fields := map[string]interface{}{}
+	addGuestsFields(ms, false, fields)
+	require.Equal(t, uint64(2), fields["guest_participants"])
+	require.NotContains(t, fields, "waiting_guests")
+
+	var a Attendee
+	require.NoError(t, xml.Unmarshal([]byte("<attendee><userID>w_1</userID><guest>true</guest></attendee>"), &a))
+	require.True(t, a.Guest)
+}
+
+func TestBigBlueButtonWaitingGuests(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherGuests = true
+	plugin.EnrichMeetingInfo = true
+	plugin.MeetingInfoLimit = 1
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	waiting, ok := acc.Uint64Field("bigbluebutton", "waiting_guests")
+	require.True(t, ok)
+	require.Equal(t, uint64(2), waiting)
+}
+
+func TestBigBlueButtonSkipIdle(t *testing.T) {
+	emptyState = true
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{"tenant"})
+	plugin.SkipIdle = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Metrics)
+
+	emptyState = false
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasMeasurement("bigbluebutton"))
+}
+
+func TestBigBlueButtonVideoPublishers(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherVideoPublishers = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	record := getExpectedValues()
+	record["video_publishers"] = 1
+	record["video_viewers"] = 4
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonBackfill(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Backfill = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	expected := map[time.Time]map[string]interface{}{
+		time.Date(2018, 7, 4, 0, 0, 0, 0, time.UTC): {
+			"recordings":           uint64(1),
+			"published_recordings": uint64(1),
+			"size_bytes":           uint64(2048),
+		},
+		time.Date(2018, 6, 29, 0, 0, 0, 0, time.UTC): {
+			"recordings":           uint64(1),
+			"published_recordings": uint64(0),
+			"size_bytes":           uint64(0),
+		},
+	}
+	days := map[time.Time]map[string]interface{}{}
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "bigbluebutton_recordings_daily" {
+			days[m.Time()] = m.Fields()
+		}
+	}
+	require.Equal(t, expected, days)
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(acc))
+	require.False(t, acc.HasMeasurement("bigbluebutton_recordings_daily"))
+}
+
+func TestBigBlueButtonBackfillPages(t *testing.T) {
+	emptyState = false
+	pages := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if r.URL.Path != "/bigbluebutton/api/getRecordings" || r.URL.Query().Get("limit") == "" {
+			body, code := getXMLResponse(r.RequestURI)
+			w.WriteHeader(code)
+			w.Write(body)
+			return
+		}
+
+		require.Equal(t, "any", r.URL.Query().Get("state"))
+		pages++
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		fmt.Fprint(w, "<response><returncode>SUCCESS</returncode><recordings>")
+		for i := offset; i < offset+100 && i < 150; i++ {
+			fmt.Fprintf(w, "<recording><recordID>r%d</recordID><published>true</published><startTime>1530718721124</startTime></recording>", i)
+		}
+		fmt.Fprint(w, "</recordings></response>")
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Backfill = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.Equal(t, 2, pages)
+	fields := map[string]interface{}{
+		"recordings":           uint64(150),
+		"published_recordings": uint64(150),
+		"size_bytes":           uint64(0),
+	}
+	acc.AssertContainsFields(t, "bigbluebutton_recordings_daily", fields)
+}
+
+func TestBigBlueButtonWebhooks(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.WebhooksListen = "127.0.0.1:0"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.Error(t, plugin.Start(acc))
+
+	plugin.WebhooksToken = "webhooks-token"
+	require.NoError(t, plugin.Start(acc))
+	defer plugin.Stop()
+
+	events := `[{"data":{"type":"event","id":"meeting-created"}},{"data":{"type":"event","id":"user-joined"}},` +
+		`{"data":{"type":"event","id":"user-joined"}},{"data":{"type":"event","id":"rap-archive-ended"}},` +
+		`{"data":{"type":"event","id":"user-audio-voice-enabled"}}]`
+	form := url.Values{"event": {events}, "timestamp": {"1530718721124"}}
+	req := httptest.NewRequest(http.MethodPost, "/webhooks?token=wrong", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw := httptest.NewRecorder()
+	plugin.webhooks.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusUnauthorized, rw.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks?token=webhooks-token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rw = httptest.NewRecorder()
+	plugin.webhooks.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/webhooks?token=webhooks-token", strings.NewReader("["+strings.Repeat(" ", webhooksMaxBodySize)+"]"))
+	req.Header.Set("Content-Type", "application/json")
+	rw = httptest.NewRecorder()
+	plugin.webhooks.ServeHTTP(rw, req)
+	require.Equal(t, http.StatusBadRequest, rw.Code)
+
+	require.NoError(t, plugin.Gather(acc))
+	fields := map[string]interface{}{
+		"meeting_created":   uint64(1),
+		"meeting_ended":     uint64(0),
+		"user_joined":       uint64(2),
+		"user_left":         uint64(0),
+		"rap_archive_ended": uint64(1),
+	}
+	acc.AssertContainsFields(t, "bigbluebutton_webhooks", fields)
+}
+
+func TestUniqueUsers(t *testing.T) {
+	now := time.Now()
+	u := newUniqueUsers()
+
+	users := make([]string, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		users = append(users, fmt.Sprintf("user-%d", i))
+	}
+	u.Add(now.Add(-2*time.Hour), users[:3000])
+	u.Add(now, users[2000:])
+
+	require.InEpsilon(t, 3000, u.Count(now, time.Hour), 0.05)
+	require.InEpsilon(t, 5000, u.Count(now, 24*time.Hour), 0.05)
+}
+
+func TestHourlyProfile(t *testing.T) {
+	now := time.Now().Truncate(time.Hour)
+	p := newHourlyProfile()
+
+	fields := p.Add(now.Add(-week), 2, 10)
+	require.NotContains(t, fields, "participants_hour_avg_last_week")
+
+	p.Add(now, 1, 4)
+	fields = p.Add(now.Add(time.Minute), 3, 8)
+	require.Equal(t, 2.0, fields["meetings_hour_avg"])
+	require.Equal(t, 6.0, fields["participants_hour_avg"])
+	require.Equal(t, 2.0, fields["meetings_hour_avg_last_week"])
+	require.Equal(t, 10.0, fields["participants_hour_avg_last_week"])
+}
+
+func TestBigBlueButtonExtraParams(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+	plugin.ExtraParams = map[string]map[string]string{
+		"getRecordings": {"state": "any"},
+	}
+	require.NoError(t, plugin.Init())
+
+	checksum := fmt.Sprintf("%x", plugin.checksum("getRecordings", "state=any"))
+	require.Equal(t, fmt.Sprintf("http://localhost/bigbluebutton/api/getRecordings?state=any&checksum=%s", checksum), plugin.endpoints.Load().getRecordings)
+	require.NotContains(t, plugin.endpoints.Load().getMeetings, "state=any")
+}
+
+func TestBigBlueButtonChecksumAlgorithm(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+	plugin.ChecksumAlgorithm = "sha256"
+	require.NoError(t, plugin.Init())
+
+	checksum := sha256.Sum256([]byte("getMeetings" + plugin.SecretKey))
+	require.Equal(t, fmt.Sprintf("http://localhost/bigbluebutton/api/getMeetings?checksum=%x", checksum), plugin.endpoints.Load().getMeetings)
+
+	plugin = getPlugin("http://localhost", []string{})
+	plugin.ChecksumAlgorithm = "md5"
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonChecksumAlgorithmAuto(t *testing.T) {
+	emptyState = false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		// only accept sha256 checksums
+		if checksum := r.URL.Query().Get("checksum"); checksum != "" && len(checksum) != 64 {
+			w.Write([]byte("<response><returncode>FAILED</returncode><messageKey>checksumError</messageKey></response>"))
+			return
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.ChecksumAlgorithm = "auto"
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "sha256", plugin.hashAlgorithm)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	acc.AssertContainsTaggedFields(t, "bigbluebutton", toStringMapInterface(getExpectedValues()), map[string]string{
+		"version":            "2.0",
+		"checksum_algorithm": "sha256",
+	})
+}
+
+func TestBigBlueButtonSecretKeysRotation(t *testing.T) {
+	emptyState = false
+	accepted := "old-secret"
+	var mu sync.Mutex
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		mu.Lock()
+		secret := accepted
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/getMeetings") {
+			expected := fmt.Sprintf("%x", sha1.Sum([]byte("getMeetings"+secret)))
+			if r.URL.Query().Get("checksum") != expected {
+				w.Write([]byte("<response><returncode>FAILED</returncode><messageKey>checksumError</messageKey></response>"))
+				return
+			}
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.SecretKey = "old-secret"
+	plugin.SecretKeys = []string{"new-secret"}
+	require.NoError(t, plugin.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Equal(t, "old-secret", plugin.secret)
+
+	mu.Lock()
+	accepted = "new-secret"
+	mu.Unlock()
+	acc = &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Equal(t, "new-secret", plugin.secret)
+	auth, ok := acc.Uint64Field("bigbluebutton", "auth_ok")
+	require.True(t, ok)
+	require.Equal(t, uint64(1), auth)
+
+	// the accepted secret key is remembered
+	require.NoError(t, plugin.Init())
+	require.Equal(t, "new-secret", plugin.secret)
+
+	mu.Lock()
+	accepted = "unknown-secret"
+	mu.Unlock()
+	acc = &testutil.Accumulator{}
+	require.Error(t, plugin.Gather(acc))
+	require.Equal(t, "new-secret", plugin.secret)
+}
+
+func TestBigBlueButtonStartupCheck(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.StartupCheck = true
+	require.NoError(t, plugin.Init())
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if strings.HasSuffix(r.URL.Path, "/getMeetings") {
+			w.Write([]byte("<response><returncode>FAILED</returncode><messageKey>checksumError</messageKey></response>"))
+			return
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer rejecting.Close()
+
+	plugin = getPlugin(rejecting.URL, []string{})
+	plugin.StartupCheck = true
+	err := plugin.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "check secret_key")
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.PathPrefix = "/wrong"
+	plugin.StartupCheck = true
+	err = plugin.Init()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "check url and path_prefix")
+}
+
+func TestBigBlueButtonHostHeaderAndTLSServerName(t *testing.T) {
+	emptyState = false
+	var mu sync.Mutex
+	hosts := map[string]bool{}
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts[r.Host] = true
+		mu.Unlock()
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	ca, err := ioutil.TempFile("", "bbb_ca")
+	require.NoError(t, err)
+	defer os.Remove(ca.Name())
+	require.NoError(t, pem.Encode(ca, &pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}))
+	require.NoError(t, ca.Close())
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.HostHeader = "bbb.example.com"
+	plugin.TLSCA = ca.Name()
+	// the httptest certificate is issued for example.com
+	plugin.ServerName = "example.com"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Equal(t, map[string]bool{"bbb.example.com": true}, hosts)
+
+	plugin = getPlugin(s.URL, []string{})
+	plugin.TLSCA = ca.Name()
+	plugin.ServerName = "bbb.example.org"
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Gather(&testutil.Accumulator{}))
+}
+
+func TestBigBlueButtonRecordingsStates(t *testing.T) {
+	emptyState = false
+	var mu sync.Mutex
+	states := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bigbluebutton/api/getRecordings" {
+			mu.Lock()
+			states = append(states, r.URL.Query().Get("state"))
+			mu.Unlock()
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.RecordingsStates = []string{"published", "processing"}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.ElementsMatch(t, []string{"published", "processing"}, states)
+	// both responses return the same recordings, merged once
+	record := getExpectedValues()
+	record["recordings_published_delta"] = 0
+	record["recordings_failed"] = 0
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonRecordingStates(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherRecordingStates = true
+	require.NoError(t, plugin.Init())
+	require.Contains(t, plugin.endpoints.Load().getRecordings, "?state=any&checksum=")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	record := getExpectedValues()
+	record["processing_recordings"] = 0
+	record["processed_recordings"] = 0
+	record["unpublished_recordings"] = 1
+	record["deleted_recordings"] = 0
+	record["recordings_published_delta"] = 0
+	record["recordings_failed"] = 0
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonPlaybackProbe(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	playback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "HEAD", r.Method)
+		require.Equal(t, "/playback/ffbfc4cc24428694e8b53a4e144f414052431693-1530718721124", r.URL.Path)
+		w.WriteHeader(200)
+	}))
+	defer playback.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.PlaybackURLTemplate = playback.URL + "/playback/{{record_id}}"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	reachable, ok := acc.Uint64Field("bigbluebutton", "playback_reachable")
+	require.True(t, ok)
+	require.Equal(t, uint64(1), reachable)
+}
+
+func TestBigBlueButtonGroupByOriginServer(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GroupByOriginServer = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("bigbluebutton_origin", map[string]string{"origin_server": "greenlight.example.com"}, "participants", uint64(5)))
+}
+
+func TestBigBlueButtonGroupByOriginServerStripPrefix(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GroupByOriginServer = true
+	plugin.GatherDistinctOrigins = true
+	plugin.PerMeetingMetrics = true
+	plugin.MeetingMetadataFields = []string{"bbb-origin-server-name"}
+	plugin.MetadataKeyStripPrefix = "bbb-"
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+
+	require.True(t, acc.HasPoint("bigbluebutton_origin", map[string]string{"origin_server": "greenlight.example.com"}, "participants", uint64(5)))
+	m, ok := acc.Get("bigbluebutton")
+	require.True(t, ok)
+	require.Equal(t, uint64(1), m.Fields["distinct_origins"])
+	require.True(t, acc.HasStringField("bigbluebutton_meeting", "origin-server-name"))
+}
+
+func TestParseMeetingsResponseMalformedEntry(t *testing.T) {
+	body := []byte(`<response>
+	<returncode>SUCCESS</returncode>
+	<meetings>
+		<meeting><participantCount>3</participantCount></meeting>
+		<meeting><participantCount>not a number</participantCount></meeting>
+		<meeting><attendees><attendee><hasVideo>maybe</hasVideo><role>VIEWER</role></attendee></attendees><participantCount>1</participantCount></meeting>
+		<meeting><participantCount>2</participantCount></meeting>
+	</meetings>
+</response>`)
+
+	response, err := parseMeetingsResponse(bytes.NewReader(body), true)
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", response.ReturnCode)
+	require.Len(t, response.Meetings.Values, 2)
+	require.Equal(t, uint64(2), response.Meetings.Values[1].ParticipantCount)
+	require.Equal(t, uint64(2), response.ParseErrors)
+
+	response, err = parseMeetingsResponse(bytes.NewReader(body[:120]), true)
+	require.NoError(t, err)
+	require.Len(t, response.Meetings.Values, 1)
+	require.Equal(t, uint64(1), response.ParseErrors)
+}
+
+func TestParseRecordingsResponse(t *testing.T) {
+	body, err := ioutil.ReadFile("testdata/getRecordings.xml")
+	require.NoError(t, err)
+
+	response, err := parseRecordingsResponse(bytes.NewReader(body), false, true)
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", response.ReturnCode)
+	require.Len(t, response.Recordings.Values, 2)
+	require.Equal(t, uint64(2048), response.Recordings.Values[0].Size)
+	require.Len(t, response.Recordings.Values[1].Playback, 2)
+	require.Nil(t, response.Recordings.Values[0].Metadata.Inner)
+
+	require.Equal(t, uint64(2), response.Summary.Recordings)
+
+	response, err = parseRecordingsResponse(bytes.NewReader(body), true, true)
+	require.NoError(t, err)
+	require.NotNil(t, response.Recordings.Values[0].Metadata.Inner)
+
+	// recordings are only summed up when not kept
+	response, err = parseRecordingsResponse(bytes.NewReader(body), false, false)
+	require.NoError(t, err)
+	require.Empty(t, response.Recordings.Values)
+	require.Equal(t, uint64(2), response.Summary.Recordings)
+	require.Equal(t, uint64(1), response.Summary.PublishedRecordings)
+
+	_, err = parseRecordingsResponse(strings.NewReader("<html></html>"), false, false)
+	require.Error(t, err)
+	_, err = parseRecordingsResponse(bytes.NewReader(body[:200]), false, false)
+	require.Error(t, err)
+}
+
+func TestBigBlueButtonCollectorTimeBudget(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+	require.True(t, plugin.withinTimeBudget(time.Now().Add(-time.Hour)))
+
+	plugin.CollectorTimeBudget = config.Duration(10 * time.Second)
+	require.True(t, plugin.withinTimeBudget(time.Now().Add(-time.Second)))
+	require.False(t, plugin.withinTimeBudget(time.Now().Add(-9*time.Second)))
+}
+
+func TestBigBlueButtonCollectorTimeBudgetRecordings(t *testing.T) {
+	emptyState = false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.RequestURI, "getRecordings") {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(2 * time.Second):
+			}
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.CollectorTimeBudget = config.Duration(200 * time.Millisecond)
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	record := getExpectedValues()
+	delete(record, "recordings")
+	delete(record, "published_recordings")
+	record["collectors_skipped"] = 1
+	acc.AssertContainsFields(t, "bigbluebutton", toStringMapInterface(record))
+}
+
+func TestBigBlueButtonOfflineBackoff(t *testing.T) {
+	plugin := getPlugin("http://localhost", []string{})
+	plugin.OfflineBackoff = config.Duration(time.Minute)
+	plugin.OfflineBackoffMax = config.Duration(3 * time.Minute)
+
+	plugin.updateBackoff(fmt.Errorf("offline"))
+	require.Equal(t, time.Minute, plugin.backoff)
+	plugin.updateBackoff(fmt.Errorf("offline"))
+	require.Equal(t, 2*time.Minute, plugin.backoff)
+	plugin.updateBackoff(fmt.Errorf("offline"))
+	require.Equal(t, 3*time.Minute, plugin.backoff)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	plugin.updateBackoff(nil)
+	require.Equal(t, time.Duration(0), plugin.backoff)
+	require.True(t, plugin.nextAttempt.IsZero())
+}
+
+func TestBigBlueButtonProbe(t *testing.T) {
+	emptyState = false
+	var mu sync.Mutex
+	meetingIDs := map[string]bool{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.URL.Query().Get("meetingID"); strings.HasPrefix(id, probeMeetingIDPrefix) {
+			mu.Lock()
+			meetingIDs[id] = true
+			mu.Unlock()
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.Probe = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	for _, step := range []string{"create", "join", "end"} {
+		require.True(t, acc.HasPoint("bigbluebutton_probe", map[string]string{"step": step}, "success", uint64(1)))
+	}
+
+	// every step uses the instance probe meeting, distinct from the probe meeting of other instances
+	require.Equal(t, map[string]bool{plugin.probeMeetingID: true}, meetingIDs)
+	other := getPlugin(s.URL, []string{})
+	require.NoError(t, other.Init())
+	require.NotEqual(t, plugin.probeMeetingID, other.probeMeetingID)
+	probeMeetingID := plugin.probeMeetingID
+	require.NoError(t, plugin.Init())
+	require.Equal(t, probeMeetingID, plugin.probeMeetingID)
+}
+
+func TestBigBlueButtonGatherByMetadataFile(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	file, err := ioutil.TempFile("", "metadata")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString("# tenant keys\ntenant\n")
+	require.NoError(t, err)
+	file.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.GatherByMetadataFile = file.Name()
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.True(t, acc.HasPoint("tenant", map[string]string{"tenant": "localhost"}, "meetings", uint64(1)))
+}
+
+func TestBigBlueButtonEnrichMeetingInfo(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.EnrichMeetingInfo = true
+	plugin.MeetingInfoLimit = 1
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	// participant counts are kept from getMeetings, getMeetingInfo only adds details
+	participants, ok := acc.Uint64Field("bigbluebutton", "participants")
+	require.True(t, ok)
+	require.Equal(t, uint64(15), participants)
+
+	moderators, ok := acc.Uint64Field("bigbluebutton", "moderators")
+	require.True(t, ok)
+	require.Equal(t, uint64(2), moderators)
+
+	m, err := plugin.getMeetings()
+	require.NoError(t, err)
+	plugin.enrichMeetings(acc, m.Meetings.Values)
+	enriched := m.Meetings.Values[1]
+	require.Equal(t, uint64(10), enriched.ParticipantCount)
+	require.Len(t, enriched.Attendees, 1)
+}
+
+func TestBigBlueButtonMeetingInfoCache(t *testing.T) {
+	emptyState = false
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.RequestURI, "getMeetingInfo") {
+			calls++
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.EnrichMeetingInfo = true
+	plugin.MeetingInfoLimit = 1
+	plugin.MeetingInfoCacheTTL = config.Duration(time.Minute)
+	require.NoError(t, plugin.Init())
+
+	for i := 0; i < 3; i++ {
+		acc := &testutil.Accumulator{}
+		require.NoError(t, plugin.Gather(acc))
+		require.Empty(t, acc.Errors)
+	}
+
+	require.Equal(t, 1, calls)
+}
+
+func TestBigBlueButtonMeetingInfoMaxConcurrentRequests(t *testing.T) {
+	emptyState = false
+	var mu sync.Mutex
+	inFlight, maxInFlight, calls := 0, 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.RequestURI, "getMeetingInfo") {
+			mu.Lock()
+			calls++
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.EnrichMeetingInfo = true
+	plugin.MaxConcurrentRequests = 1
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.Equal(t, 2, calls)
+	require.Equal(t, 1, maxInFlight)
+}
+
+func TestBigBlueButtonServers(t *testing.T) {
+	emptyState = false
+	s1 := getHTTPServer()
+	defer s1.Close()
+	s2 := getHTTPServer()
+	defer s2.Close()
+
+	balancer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header()["Date"] = nil
+		if r.URL.Path != "/scalelite/api/getServers" {
+			body, code := getXMLResponse(r.RequestURI)
+			w.WriteHeader(code)
+			w.Write(body)
+			return
+		}
+		fmt.Fprintf(w, `<response><returncode>SUCCESS</returncode><servers>
+			<server><serverURL>%s/bigbluebutton/api</serverURL><state>cordoned</state></server>
+		</servers></response>`, s1.URL)
+	}))
+	defer balancer.Close()
+
+	plugin := BigBlueButton{
+		SecretKey:   "OxShRR1sT8FrJZq",
+		BalancerURL: balancer.URL,
+		Servers: []Server{
+			{Name: "bbb1", URL: s1.URL},
+			{URL: s2.URL},
+		},
+	}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.True(t, acc.HasPoint("bigbluebutton", map[string]string{"server": "bbb1", "balancer_state": "cordoned", "version": "2.0"}, "meetings", uint64(2)))
+	require.True(t, acc.HasPoint("bigbluebutton", map[string]string{"server": s2.URL, "version": "2.0"}, "meetings", uint64(2)))
+}
+
+func TestBigBlueButtonServersParentOptions(t *testing.T) {
+	emptyState = false
+	var mu sync.Mutex
+	scaleliteCalls := 0
+	s1 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/scalelite") {
+			mu.Lock()
+			scaleliteCalls++
+			mu.Unlock()
+		}
+		body, code := getXMLResponse(r.RequestURI)
+		w.Header()["Date"] = nil
+		w.WriteHeader(code)
+		w.Write(body)
+	}))
+	defer s1.Close()
+
+	// the balancer only answers getServers, a startup check against it would fail
+	balancer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scalelite/api/getServers" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `<response><returncode>SUCCESS</returncode><servers></servers></response>`)
+	}))
+	defer balancer.Close()
+
+	plugin := BigBlueButton{
+		SecretKey:        "OxShRR1sT8FrJZq",
+		BalancerURL:      balancer.URL,
+		ScaleliteMode:    true,
+		StartupCheck:     true,
+		DebugMemoryStats: true,
+		Servers:          []Server{{Name: "bbb1", URL: s1.URL}},
+	}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.Equal(t, 0, scaleliteCalls)
+	debug := 0
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "bigbluebutton_debug" {
+			debug++
+		}
+	}
+	require.Equal(t, 1, debug)
+
+	// servers are still checked on startup
+	plugin = BigBlueButton{
+		SecretKey:    "OxShRR1sT8FrJZq",
+		StartupCheck: true,
+		Servers:      []Server{{Name: "bbb1", URL: s1.URL, PathPrefix: "/unknown"}},
+	}
+	require.Error(t, plugin.Init())
+}
+
+func TestBigBlueButtonSetServers(t *testing.T) {
+	emptyState = false
+	s1 := getHTTPServer()
+	defer s1.Close()
+	s2 := getHTTPServer()
+	defer s2.Close()
+
+	plugin := BigBlueButton{
+		SecretKey: "OxShRR1sT8FrJZq",
+		Servers:   []Server{{Name: "bbb1", URL: s1.URL}},
+	}
+	require.NoError(t, plugin.Init())
+	first := plugin.servers.load()[0]
+
+	// gathers run while the server list changes
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 5; i++ {
+			plugin.Gather(&testutil.Accumulator{})
+		}
+	}()
+	// concurrent writers are serialized, the second one reuses the servers of the first
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = plugin.setServers([]Server{{Name: "bbb1", URL: s1.URL}, {Name: "bbb2", URL: s2.URL}})
+		}(i)
+	}
+	wg.Wait()
+	<-done
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	servers := plugin.servers.load()
+	require.Len(t, servers, 2)
+	require.Same(t, first, servers[0])
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.True(t, acc.HasPoint("bigbluebutton", map[string]string{"server": "bbb2", "version": "2.0"}, "meetings", uint64(2)))
+
+	require.Error(t, plugin.setServers([]Server{{URL: "ftp://invalid"}}))
+	require.Len(t, plugin.servers.load(), 2)
+}
+
+func TestBigBlueButtonInitReentrant(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	require.NoError(t, plugin.Init())
+	recordings := plugin.recordings
+
+	plugin.ChecksumAlgorithm = "sha256"
+	require.NoError(t, plugin.Init())
+	require.Same(t, recordings, plugin.recordings)
+	require.Equal(t, "sha256", plugin.hashAlgorithm)
+	require.Equal(t, "sha256", plugin.ChecksumAlgorithm)
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+}
+
+func TestBigBlueButtonScaleliteMode(t *testing.T) {
+	emptyState = false
+	s := getHTTPServer()
+	defer s.Close()
+
+	plugin := getPlugin(s.URL, []string{})
+	plugin.ScaleliteMode = true
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	tags := map[string]string{
+		"server_id": "1f0e2a7b-5c44-4b6e-9a7d-2d1c8e4f3b10",
+		"server":    "bbb2.example.com",
+		"state":     "cordoned",
+	}
+	fields := map[string]interface{}{
+		"load":            4.0,
+		"load_multiplier": 2.0,
+		"meetings":        uint64(1),
+		"online":          uint64(1),
+		"enabled":         uint64(0),
+		"cordoned":        uint64(1),
+	}
+	acc.AssertContainsTaggedFields(t, "bigbluebutton_scalelite_server", fields, tags)
+}
+
+func TestBigBlueButtonBigBlueSwarm(t *testing.T) {
+	emptyState = false
+	bbb := getHTTPServer()
+	defer bbb.Close()
+
+	swarm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "api-key", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/bigblueswarm/api/instances":
+			fmt.Fprintf(w, `{"kind":"InstanceList","instances":{"%s/bigbluebutton":"OxShRR1sT8FrJZq"}}`, bbb.URL)
+		case "/bigblueswarm/api/tenants":
+			w.Write([]byte(`{"kind":"TenantList","tenants":[{"hostname":"localhost","instance_count":1}]}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer swarm.Close()
+
+	plugin := BigBlueButton{
+		BigBlueSwarmURL:    swarm.URL,
+		BigBlueSwarmAPIKey: "api-key",
+	}
+	require.NoError(t, plugin.Init())
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Gather(acc))
+	require.Empty(t, acc.Errors)
+
+	require.True(t, acc.HasPoint("bigbluebutton_bigblueswarm_tenant", map[string]string{"tenant": "localhost"}, "instances", uint64(1)))
+	require.True(t, acc.HasPoint("bigbluebutton", map[string]string{"server": bbb.URL + "/bigbluebutton", "version": "2.0"}, "meetings", uint64(2)))
+}
+
+func TestBigBlueButtonSSHTunnelConfig(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	file, err := ioutil.TempFile("", "id_ed25519")
+	require.NoError(t, err)
+	defer os.Remove(file.Name())
+	require.NoError(t, pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	file.Close()
+
+	plugin := getPlugin("http://10.0.0.1", []string{})
+	plugin.SSHTunnelHost = "jump.example.com"
+	plugin.SSHTunnelUser = "telegraf"
+	plugin.SSHTunnelKey = file.Name()
+	require.Error(t, plugin.Init())
+
+	plugin.SSHTunnelInsecureIgnoreHostKey = true
+	require.NoError(t, plugin.Init())
+
+	// a jump host accepting connections without answering the ssh handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	plugin.SSHTunnelHost = listener.Addr().String()
+	plugin.ConnectTimeout = config.Duration(100 * time.Millisecond)
+	tunnel, err := plugin.newSSHTunnel()
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = tunnel.DialContext(context.Background(), "tcp", "10.0.0.1:80")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	plugin.ConnectTimeout = config.Duration(time.Minute)
+	tunnel, err = plugin.newSSHTunnel()
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = tunnel.DialContext(ctx, "tcp", "10.0.0.1:80")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+}
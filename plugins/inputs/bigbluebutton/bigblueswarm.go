@@ -0,0 +1,114 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/influxdata/telegraf"
+)
+
+// BigBlueSwarmInstances is the BigBlueSwarm admin api instance list. Instances maps instance urls to their secret
+type BigBlueSwarmInstances struct {
+	Kind      string            `json:"kind"`
+	Instances map[string]string `json:"instances"`
+}
+
+// BigBlueSwarmTenants is the BigBlueSwarm admin api tenant list
+type BigBlueSwarmTenants struct {
+	Kind    string               `json:"kind"`
+	Tenants []BigBlueSwarmTenant `json:"tenants"`
+}
+
+// BigBlueSwarmTenant is a BigBlueSwarm tenant
+type BigBlueSwarmTenant struct {
+	Hostname      string `json:"hostname"`
+	InstanceCount uint64 `json:"instance_count"`
+}
+
+func (b *BigBlueButton) initBigBlueSwarm() error {
+	u, err := parseServerURL(b.BigBlueSwarmURL)
+	if err != nil {
+		return err
+	}
+	b.bigBlueSwarmURL = u
+
+	return b.initClient()
+}
+
+// bigBlueSwarmAdmin calls a BigBlueSwarm admin api endpoint and decodes the json response
+func (b *BigBlueButton) bigBlueSwarmAdmin(endpoint string, v interface{}) error {
+	u := *b.bigBlueSwarmURL
+	u.Path = path.Join("/", u.Path, "bigblueswarm", "api", endpoint)
+
+	request, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", b.BigBlueSwarmAPIKey)
+
+	resp, err := b.client.Load().Do(request)
+	if err != nil {
+		return fmt.Errorf("error getting bigblueswarm %s: %s", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("error getting bigblueswarm %s: status %d", endpoint, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// gatherBigBlueSwarm discovers the balancer instances and tenants. Each instance is gathered with its own secret
+// and tagged with server, tenants are emitted in the bigbluebutton_bigblueswarm_tenant measurement
+func (b *BigBlueButton) gatherBigBlueSwarm(acc telegraf.Accumulator) error {
+	var instances BigBlueSwarmInstances
+	if err := b.bigBlueSwarmAdmin("instances", &instances); err != nil {
+		return err
+	}
+	b.syncBigBlueSwarmInstances(acc, instances)
+
+	var tenants BigBlueSwarmTenants
+	if err := b.bigBlueSwarmAdmin("tenants", &tenants); err != nil {
+		acc.AddError(err)
+	}
+
+	for _, t := range tenants.Tenants {
+		acc.AddFields("bigbluebutton_bigblueswarm_tenant", map[string]interface{}{"instances": t.InstanceCount}, map[string]string{"tenant": t.Hostname})
+	}
+
+	b.gatherServers(acc)
+	return nil
+}
+
+// syncBigBlueSwarmInstances updates the gathered servers from the discovered instances, keeping the state of known instances
+func (b *BigBlueButton) syncBigBlueSwarmInstances(acc telegraf.Accumulator, instances BigBlueSwarmInstances) {
+	if b.servers == nil {
+		b.servers = &serverSet{}
+	}
+	b.servers.mu.Lock()
+	defer b.servers.mu.Unlock()
+
+	known := b.knownServers()
+	servers := make([]*gatheredServer, 0, len(instances.Instances))
+	for instanceURL, secret := range instances.Instances {
+		// instance urls already contain the BigBlueButton path
+		s, err := b.reuseOrNewGatheredServer(known, Server{URL: instanceURL, SecretKey: secret, PathPrefix: "/"})
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		servers = append(servers, s)
+	}
+
+	b.servers.store(servers)
+}
@@ -0,0 +1,39 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+// groupBreakouts folds breakout rooms into their parent meeting: breakout participants and attendees are counted
+// in the parent. Breakout rooms whose parent is not running are kept as is
+func groupBreakouts(ms []Meeting) []Meeting {
+	grouped := make([]Meeting, 0, len(ms))
+	parents := make(map[string]int, len(ms))
+	for _, m := range ms {
+		if m.IsBreakout {
+			continue
+		}
+		m.Attendees = append([]Attendee{}, m.Attendees...)
+		parents[m.InternalMeetingID] = len(grouped)
+		grouped = append(grouped, m)
+	}
+
+	for _, br := range ms {
+		if !br.IsBreakout {
+			continue
+		}
+
+		i, ok := parents[br.ParentMeetingID]
+		if !ok {
+			grouped = append(grouped, br)
+			continue
+		}
+
+		parent := &grouped[i]
+		parent.ParticipantCount += br.ParticipantCount
+		parent.ListenerCount += br.ListenerCount
+		parent.VoiceParticipantCount += br.VoiceParticipantCount
+		parent.VideoCount += br.VideoCount
+		parent.ModeratorCount += br.ModeratorCount
+		parent.Attendees = append(parent.Attendees, br.Attendees...)
+	}
+
+	return grouped
+}
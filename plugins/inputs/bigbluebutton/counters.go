@@ -0,0 +1,37 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import "time"
+
+// counterTracker maintains monotonically increasing totals between gathers. Totals restart from zero
+// when Telegraf restarts, which counter based backends handle as a counter reset
+type counterTracker struct {
+	initialized        bool
+	last               time.Time
+	meetings           map[string]bool
+	participantMinutes float64
+	meetingStarts      uint64
+}
+
+// Update adds the participant minutes elapsed since the previous update, based on the current participants,
+// and the meetings started since the previous update. Meetings running on the first update are not counted as started
+func (t *counterTracker) Update(ms []Meeting, now time.Time) (participantMinutes float64, meetingStarts uint64) {
+	meetings := make(map[string]bool, len(ms))
+	participants := uint64(0)
+	for _, m := range ms {
+		meetings[m.InternalMeetingID] = true
+		participants += m.ParticipantCount
+		if t.initialized && !t.meetings[m.InternalMeetingID] {
+			t.meetingStarts++
+		}
+	}
+
+	if t.initialized {
+		t.participantMinutes += float64(participants) * now.Sub(t.last).Minutes()
+	}
+
+	t.initialized = true
+	t.last = now
+	t.meetings = meetings
+	return t.participantMinutes, t.meetingStarts
+}
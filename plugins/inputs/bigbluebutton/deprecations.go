@@ -0,0 +1,49 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+// deprecatedOption is a renamed or replaced configuration option. The old option is kept as a struct field
+// so that existing configurations still load, and migrated to its replacement on Init
+type deprecatedOption struct {
+	name        string
+	replacement string
+	// isSet check if the deprecated option is set in the configuration
+	isSet func(b *BigBlueButton) bool
+	// migrate maps the deprecated option value to its replacement
+	migrate func(b *BigBlueButton)
+}
+
+// deprecatedOptions lists the deprecated configuration options, oldest first
+var deprecatedOptions = []deprecatedOption{
+	{
+		// static per server states were replaced by the states reported by the Scalelite getServers api
+		name:        "servers.balancer_state",
+		replacement: "balancer_url",
+		isSet: func(b *BigBlueButton) bool {
+			for _, s := range b.Servers {
+				if s.BalancerState != "" {
+					return true
+				}
+			}
+			return false
+		},
+		migrate: func(b *BigBlueButton) {
+			for i := range b.Servers {
+				b.Servers[i].BalancerState = ""
+			}
+		},
+	},
+}
+
+// migrateDeprecatedOptions maps deprecated options to their replacement and logs a deprecation warning for each of them
+func (b *BigBlueButton) migrateDeprecatedOptions() {
+	for _, o := range deprecatedOptions {
+		if !o.isSet(b) {
+			continue
+		}
+
+		if b.Log != nil {
+			b.Log.Warnf("option %q is deprecated and will be removed in a future release, use %q instead", o.name, o.replacement)
+		}
+		o.migrate(b)
+	}
+}
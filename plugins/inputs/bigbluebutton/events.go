@@ -0,0 +1,45 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// eventTracker keeps the server state between gathers to detect state transitions
+type eventTracker struct {
+	initialized bool
+	online      bool
+	meetings    uint64
+}
+
+// Update stores the current server state and returns the transition events since the previous update,
+// keyed by event name with their message. No event is returned on the first update
+func (t *eventTracker) Update(online bool, meetings uint64) map[string]string {
+	events := map[string]string{}
+	if t.initialized {
+		if t.online && !online {
+			events["server_offline"] = "BigBlueButton server went offline"
+		}
+		if !t.online && online {
+			events["server_online"] = "BigBlueButton server is back online"
+		}
+		if online && t.meetings > 0 && meetings == 0 {
+			events["meetings_dropped_to_zero"] = "BigBlueButton meeting count dropped to zero"
+		}
+	}
+
+	t.initialized = true
+	t.online = online
+	if online {
+		t.meetings = meetings
+	}
+
+	return events
+}
+
+// gatherEvents emits one bigbluebutton_event point per state transition, tagged with the event name
+func (b *BigBlueButton) gatherEvents(acc telegraf.Accumulator, online bool, meetings uint64) {
+	for event, message := range b.events.Update(online, meetings) {
+		acc.AddFields("bigbluebutton_event", map[string]interface{}{"message": message}, map[string]string{"event": event})
+	}
+}
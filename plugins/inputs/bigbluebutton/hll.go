@@ -0,0 +1,120 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	hllPrecision = 10
+	hllRegisters = 1 << hllPrecision
+)
+
+// hyperLogLog is a HyperLogLog distinct count estimator
+type hyperLogLog struct {
+	registers [hllRegisters]uint8
+}
+
+// Add adds a value to the estimator
+func (h *hyperLogLog) Add(value string) {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	x := mix64(hash.Sum64())
+
+	idx := x >> (64 - hllPrecision)
+	rho := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rho > h.registers[idx] {
+		h.registers[idx] = rho
+	}
+}
+
+// Merge merges another estimator into this one
+func (h *hyperLogLog) Merge(other *hyperLogLog) {
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+}
+
+// Count returns the estimated number of distinct values
+func (h *hyperLogLog) Count() uint64 {
+	m := float64(hllRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Pow(2, -float64(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// mix64 is the splitmix64 finalizer, spreading fnv hash bits for better register distribution
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// uniqueUsers estimates distinct users over sliding windows using time bucketed HyperLogLog sketches
+type uniqueUsers struct {
+	bucketSize time.Duration
+	buckets    map[int64]*hyperLogLog
+}
+
+var uniqueUsersBucketSize = 5 * time.Minute
+
+func newUniqueUsers() *uniqueUsers {
+	return &uniqueUsers{
+		bucketSize: uniqueUsersBucketSize,
+		buckets:    map[int64]*hyperLogLog{},
+	}
+}
+
+// Add adds the users seen at the given time
+func (u *uniqueUsers) Add(now time.Time, users []string) {
+	key := now.Truncate(u.bucketSize).Unix()
+	bucket, ok := u.buckets[key]
+	if !ok {
+		bucket = &hyperLogLog{}
+		u.buckets[key] = bucket
+	}
+
+	for _, user := range users {
+		bucket.Add(user)
+	}
+
+	oldest := now.Add(-24 * time.Hour).Truncate(u.bucketSize).Unix()
+	for k := range u.buckets {
+		if k <= oldest {
+			delete(u.buckets, k)
+		}
+	}
+}
+
+// Count returns the estimated distinct users seen during the window preceding the given time
+func (u *uniqueUsers) Count(now time.Time, window time.Duration) uint64 {
+	from := now.Add(-window).Truncate(u.bucketSize).Unix()
+	merged := &hyperLogLog{}
+	for k, bucket := range u.buckets {
+		if k > from {
+			merged.Merge(bucket)
+		}
+	}
+
+	return merged.Count()
+}
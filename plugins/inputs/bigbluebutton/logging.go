@@ -0,0 +1,44 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"io"
+	"regexp"
+	"time"
+)
+
+// checksumParam matches the checksum query parameter of signed api urls
+var checksumParam = regexp.MustCompile(`checksum=[^&]*`)
+
+// redactChecksum hides the checksum of a signed api url, so logged urls can't be replayed
+func redactChecksum(u string) string {
+	return checksumParam.ReplaceAllString(u, "checksum=REDACTED")
+}
+
+// debugf logs at debug level when the plugin logger is set
+func (b *BigBlueButton) debugf(format string, args ...interface{}) {
+	if b.Log != nil {
+		b.Log.Debugf(format, args...)
+	}
+}
+
+// loggedBody counts the bytes read from a response body and logs the response size and timing once closed
+type loggedBody struct {
+	io.ReadCloser
+	plugin *BigBlueButton
+	url    string
+	start  time.Time
+	size   int64
+}
+
+func (l *loggedBody) Read(p []byte) (int, error) {
+	n, err := l.ReadCloser.Read(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *loggedBody) Close() error {
+	err := l.ReadCloser.Close()
+	l.plugin.debugf("GET %s: read %d bytes in %s", l.url, l.size, time.Since(l.start))
+	return err
+}
@@ -0,0 +1,147 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// enrichMeetings merges the getMeetingInfo details into the largest meetings.
+// Only the meeting_info_limit biggest meetings, by participant count, are enriched, with at most
+// max_concurrent_requests calls in flight
+func (b *BigBlueButton) enrichMeetings(acc telegraf.Accumulator, ms []Meeting) {
+	indexes := make([]int, len(ms))
+	for i := range ms {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return ms[indexes[i]].ParticipantCount > ms[indexes[j]].ParticipantCount
+	})
+
+	if b.MeetingInfoLimit > 0 && len(indexes) > b.MeetingInfoLimit {
+		indexes = indexes[:b.MeetingInfoLimit]
+	}
+
+	now := time.Now()
+	b.evictMeetingInfo(ms, now)
+	pending := []int{}
+	for _, i := range indexes {
+		if cached, ok := b.meetingInfoCache[ms[i].InternalMeetingID]; ok {
+			ms[i].enrich(cached.meeting)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	jobs := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < b.maxConcurrentRequests(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				m, err := b.getMeetingInfo(ms[i].MeetingID)
+				if err != nil {
+					acc.AddError(fmt.Errorf("getting meeting info for %s: %s", ms[i].MeetingID, err))
+					continue
+				}
+				ms[i].enrich(*m)
+
+				if b.MeetingInfoCacheTTL > 0 {
+					mu.Lock()
+					b.meetingInfoCache[m.InternalMeetingID] = cachedMeetingInfo{meeting: *m, fetchedAt: now}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// enrich copies the getMeetingInfo details into the meeting. Participant, voice, video and listener counts and the
+// recording state are kept from getMeetings, getMeetingInfo results may be up to meeting_info_cache_ttl old
+func (m *Meeting) enrich(info Meeting) {
+	m.Attendees = info.Attendees
+	m.GuestPolicy = info.GuestPolicy
+	m.AutoStartRecording = info.AutoStartRecording
+	m.AllowStartStopRecording = info.AllowStartStopRecording
+	m.WebcamsOnlyForModerator = info.WebcamsOnlyForModerator
+	m.MuteOnStart = info.MuteOnStart
+	m.WaitingGuests = info.WaitingGuests
+	if len(m.Metadata.Inner) == 0 && m.ParsedMetadata == nil {
+		m.MetadataStruct = info.MetadataStruct
+	}
+}
+
+// cachedMeetingInfo is a getMeetingInfo result kept for meeting_info_cache_ttl
+type cachedMeetingInfo struct {
+	meeting   Meeting
+	fetchedAt time.Time
+}
+
+// evictMeetingInfo removes expired cache entries and entries of meetings that are not running anymore
+func (b *BigBlueButton) evictMeetingInfo(ms []Meeting, now time.Time) {
+	running := make(map[string]bool, len(ms))
+	for _, m := range ms {
+		running[m.InternalMeetingID] = true
+	}
+
+	for id, cached := range b.meetingInfoCache {
+		if !running[id] || now.Sub(cached.fetchedAt) >= time.Duration(b.MeetingInfoCacheTTL) {
+			delete(b.meetingInfoCache, id)
+		}
+	}
+}
+
+func (b *BigBlueButton) getMeetingInfo(meetingID string) (*Meeting, error) {
+	body, _, err := b.api(b.signedURL("getMeetingInfo", url.Values{"meetingID": {meetingID}}))
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := parseMeetingInfoResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !b.usesMetadata() {
+		m.DiscardMetadata()
+	}
+
+	return m, nil
+}
+
+// parseMeetingInfoResponse decodes a getMeetingInfo response. Meeting fields are direct children of the response element
+func parseMeetingInfoResponse(body []byte) (*Meeting, error) {
+	var response struct {
+		APIResponse
+		Inner []byte `xml:",innerxml"`
+	}
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	if response.ReturnCode != "SUCCESS" {
+		return nil, fmt.Errorf("returncode %s, messageKey %s", response.ReturnCode, response.MessageKey)
+	}
+
+	var m Meeting
+	if err := xml.Unmarshal(append(append([]byte("<meeting>"), response.Inner...), []byte("</meeting>")...), &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
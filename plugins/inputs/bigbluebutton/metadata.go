@@ -0,0 +1,152 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var defaultGatherByMetadataFileRefresh = time.Minute
+
+// unsafeMetadataKeyChars matches the characters replaced by metadata_key_sanitize
+var unsafeMetadataKeyChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// combinedMetadataSeparator joins the keys and values of metadata grouped together by gather_by_metadata_combined
+const combinedMetadataSeparator = "\x1f"
+
+// metadataKeys returns the normalized metadata keys to gather by, from configuration and metadata file
+func (b *BigBlueButton) metadataKeys() []string {
+	if len(b.fileMetadataKeys) == 0 && !b.normalizesMetadataKeys() {
+		return b.GatherByMetadata
+	}
+
+	keys := []string{}
+	for _, k := range append(append([]string{}, b.GatherByMetadata...), b.fileMetadataKeys...) {
+		k = b.normalizeMetadataKey(k)
+		if !contains(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+
+	return keys
+}
+
+// usesMetadata returns true when an option reads the meetings or recordings metadata. Otherwise the raw metadata
+// is discarded once decoded, so large servers don't keep unused buffers for every meeting and recording
+func (b *BigBlueButton) usesMetadata() bool {
+	return len(b.metadataKeys()) > 0 || b.GatherByMetadataFile != "" || b.GroupByOriginServer ||
+		b.GatherDistinctOrigins || len(b.MeetingMetadataFields) > 0 || len(b.RecordingMetadataTags) > 0
+}
+
+// normalizesMetadataKeys returns true when a metadata key normalization option is set
+func (b *BigBlueButton) normalizesMetadataKeys() bool {
+	return b.MetadataKeyStripPrefix != "" || b.MetadataKeyLowercase || b.MetadataKeySanitize
+}
+
+// normalizeMetadataKey lowercases the key, strips the configured prefix and replaces characters other than
+// letters, digits, underscores and dashes by underscores, according to the metadata_key options
+func (b *BigBlueButton) normalizeMetadataKey(key string) string {
+	prefix := b.MetadataKeyStripPrefix
+	if b.MetadataKeyLowercase {
+		key = strings.ToLower(key)
+		prefix = strings.ToLower(prefix)
+	}
+	if prefix != "" {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	if b.MetadataKeySanitize {
+		key = unsafeMetadataKeyChars.ReplaceAllString(key, "_")
+	}
+
+	return key
+}
+
+// normalizeMetadata normalizes the parsed metadata keys
+func (b *BigBlueButton) normalizeMetadata(m *MetadataStruct) {
+	if !b.normalizesMetadataKeys() {
+		return
+	}
+
+	normalized := make(map[string]string, len(m.ParsedMetadata))
+	for k, v := range m.ParsedMetadata {
+		normalized[b.normalizeMetadataKey(k)] = v
+	}
+	m.ParsedMetadata = normalized
+}
+
+// metadataValue returns the value of a metadata key, or the joined values of combined keys. Missing keys take
+// the unknown value, ok is false when one of the keys is missing and unknown is empty
+func metadataValue(m *MetadataStruct, key string, unknown string) (string, bool) {
+	values := []string{}
+	for _, k := range strings.Split(key, combinedMetadataSeparator) {
+		if !m.ContainsMetadata(k) {
+			if unknown == "" {
+				return "", false
+			}
+			values = append(values, unknown)
+			continue
+		}
+		values = append(values, m.GetMetadata(k))
+	}
+
+	return strings.Join(values, combinedMetadataSeparator), true
+}
+
+// refreshMetadataFile re-reads the gather_by_metadata_file when the refresh interval elapsed.
+// On error, previously read keys are kept
+func (b *BigBlueButton) refreshMetadataFile(now time.Time) error {
+	if b.GatherByMetadataFile == "" {
+		return nil
+	}
+
+	refresh := time.Duration(b.GatherByMetadataFileRefresh)
+	if refresh <= 0 {
+		refresh = defaultGatherByMetadataFileRefresh
+	}
+
+	if !b.metadataFileReadAt.IsZero() && now.Sub(b.metadataFileReadAt) < refresh {
+		return nil
+	}
+
+	b.metadataFileReadAt = now
+	keys, err := readMetadataFile(b.GatherByMetadataFile)
+	if err != nil {
+		return err
+	}
+
+	b.fileMetadataKeys = keys
+	return nil
+}
+
+// readMetadataFile reads one metadata key per line. Empty lines and lines starting with # are ignored
+func readMetadataFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+
+	return keys, scanner.Err()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// probeMeetingIDPrefix prefixes the meeting identifier used by the synthetic probe
+const probeMeetingIDPrefix = "telegraf-probe"
+
+// newProbeMeetingID returns a probe meeting identifier unique to the plugin instance, suffixed with the host name and
+// a random token, so that the probes of several Telegraf instances against one server do not end each other's meeting
+func newProbeMeetingID() string {
+	token := make([]byte, 4)
+	rand.Read(token)
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return fmt.Sprintf("%s-%x", probeMeetingIDPrefix, token)
+	}
+
+	return fmt.Sprintf("%s-%s-%x", probeMeetingIDPrefix, host, token)
+}
+
+// probeStep is a synthetic probe step calling a BigBlueButton api
+type probeStep struct {
+	name   string
+	params url.Values
+}
+
+// gatherProbe runs a synthetic create, join and end scenario and emits one bigbluebutton_probe point per step.
+// Steps following a failed step are reported as failed without being run
+func (b *BigBlueButton) gatherProbe(acc telegraf.Accumulator) {
+	password := fmt.Sprintf("%x", b.checksum("probe", b.probeMeetingID))[:16]
+	steps := []probeStep{
+		{name: "create", params: url.Values{
+			"meetingID":   {b.probeMeetingID},
+			"name":        {"Telegraf probe"},
+			"moderatorPW": {password},
+		}},
+		{name: "join", params: url.Values{
+			"meetingID": {b.probeMeetingID},
+			"fullName":  {"Telegraf probe"},
+			"password":  {password},
+			"role":      {"MODERATOR"},
+			"redirect":  {"false"},
+		}},
+		{name: "end", params: url.Values{
+			"meetingID": {b.probeMeetingID},
+			"password":  {password},
+		}},
+	}
+
+	failed := false
+	for _, step := range steps {
+		fields := map[string]interface{}{"success": uint64(0)}
+		if !failed {
+			start := time.Now()
+			err := b.probe(step)
+			fields["latency_ms"] = time.Since(start).Milliseconds()
+			if err == nil {
+				fields["success"] = uint64(1)
+			} else {
+				failed = true
+				acc.AddError(fmt.Errorf("probe step %s failed: %s", step.name, err))
+			}
+		}
+
+		acc.AddFields("bigbluebutton_probe", fields, map[string]string{"step": step.name})
+	}
+}
+
+func (b *BigBlueButton) probe(step probeStep) error {
+	body, _, err := b.api(b.signedURL(step.name, step.params))
+	if err != nil {
+		return err
+	}
+
+	var response APIResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return err
+	}
+
+	if response.ReturnCode != "SUCCESS" {
+		return fmt.Errorf("returncode %s, messageKey %s", response.ReturnCode, response.MessageKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import "time"
+
+const week = 7 * 24 * time.Hour
+
+type hourStats struct {
+	samples      uint64
+	meetings     uint64
+	participants uint64
+}
+
+func (s *hourStats) meetingsAvg() float64 {
+	return float64(s.meetings) / float64(s.samples)
+}
+
+func (s *hourStats) participantsAvg() float64 {
+	return float64(s.participants) / float64(s.samples)
+}
+
+// hourlyProfile aggregates meetings and participants per hour and keeps one week of history,
+// so the current hour can be compared with the same hour last week
+type hourlyProfile struct {
+	hours map[int64]*hourStats
+}
+
+func newHourlyProfile() *hourlyProfile {
+	return &hourlyProfile{
+		hours: map[int64]*hourStats{},
+	}
+}
+
+// Add adds a sample to the current hour and returns the profile fields
+func (p *hourlyProfile) Add(now time.Time, meetings uint64, participants uint64) map[string]interface{} {
+	hour := now.Truncate(time.Hour)
+	current, ok := p.hours[hour.Unix()]
+	if !ok {
+		current = &hourStats{}
+		p.hours[hour.Unix()] = current
+	}
+
+	current.samples++
+	current.meetings += meetings
+	current.participants += participants
+
+	for k := range p.hours {
+		if k < hour.Add(-week).Unix() {
+			delete(p.hours, k)
+		}
+	}
+
+	fields := map[string]interface{}{
+		"meetings_hour_avg":     current.meetingsAvg(),
+		"participants_hour_avg": current.participantsAvg(),
+	}
+
+	if lastWeek, ok := p.hours[hour.Add(-week).Unix()]; ok {
+		fields["meetings_hour_avg_last_week"] = lastWeek.meetingsAvg()
+		fields["participants_hour_avg_last_week"] = lastWeek.participantsAvg()
+	}
+
+	return fields
+}
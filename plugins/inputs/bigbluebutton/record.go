@@ -1,6 +1,12 @@
 // Package bigbluebutton provides gather functionality
 package bigbluebutton
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Record is a telegraf acc record object
 type Record struct {
 	Meetings             uint64
@@ -8,12 +14,22 @@ type Record struct {
 	ListenerParticipants uint64
 	VoiceParticipants    uint64
 	VideoParticipants    uint64
+	Moderators           uint64
 	ActiveRecordings     uint64
 	Recordings           uint64
 	PublishedRecordings  uint64
 	Online               uint64
+	ClientTypes          map[string]uint64
+	RecordingStates      map[string]uint64
+	// PlaybackFormats counts the recordings per playback format type
+	PlaybackFormats map[string]uint64
+	// Roles counts the attendees per role (viewer, moderator), computed from meeting attendees
+	Roles map[string]uint64
 }
 
+// RecordingStates lists the BigBlueButton recording lifecycle states
+var RecordingStates = []string{"processing", "processed", "published", "unpublished", "deleted"}
+
 // NewRecord initialize a new Record struct
 func NewRecord() *Record {
 	return &Record{
@@ -22,10 +38,15 @@ func NewRecord() *Record {
 		ListenerParticipants: uint64(0),
 		VoiceParticipants:    uint64(0),
 		VideoParticipants:    uint64(0),
+		Moderators:           uint64(0),
 		ActiveRecordings:     uint64(0),
 		Recordings:           uint64(0),
 		PublishedRecordings:  uint64(0),
 		Online:               uint64(0),
+		ClientTypes:          map[string]uint64{},
+		RecordingStates:      map[string]uint64{},
+		Roles:                map[string]uint64{},
+		PlaybackFormats:      map[string]uint64{},
 	}
 }
 
@@ -47,6 +68,7 @@ func (rec *Record) ToMap() map[string]uint64 {
 		"listener_participants": rec.ListenerParticipants,
 		"voice_participants":    rec.VoiceParticipants,
 		"video_participants":    rec.VideoParticipants,
+		"moderators":            rec.Moderators,
 		"active_recordings":     rec.ActiveRecordings,
 		"recordings":            rec.Recordings,
 		"published_recordings":  rec.PublishedRecordings,
@@ -54,6 +76,69 @@ func (rec *Record) ToMap() map[string]uint64 {
 	}
 }
 
+// ClientTypesMap returns the participants per client type as a valid map[string]uint64
+func (rec *Record) ClientTypesMap() map[string]uint64 {
+	m := make(map[string]uint64, len(rec.ClientTypes))
+	for t, v := range rec.ClientTypes {
+		m[fmt.Sprintf("%s_participants", t)] = v
+	}
+
+	return m
+}
+
+// PlaybackFormats lists the BigBlueButton recording playback formats
+var PlaybackFormats = []string{"presentation", "video", "podcast", "screenshare", "notes"}
+
+// PlaybackFormatsMap returns the recordings per playback format as <format>_format_recordings fields. Known formats
+// are always present
+func (rec *Record) PlaybackFormatsMap() map[string]uint64 {
+	m := make(map[string]uint64, len(PlaybackFormats))
+	for _, f := range PlaybackFormats {
+		m[fmt.Sprintf("%s_format_recordings", f)] = 0
+	}
+	for f, v := range rec.PlaybackFormats {
+		m[fmt.Sprintf("%s_format_recordings", f)] = v
+	}
+
+	return m
+}
+
+// AttendeeRoles lists the BigBlueButton attendee roles
+var AttendeeRoles = []string{"viewer", "moderator"}
+
+// RolesMap returns the attendees per role as <role>_participants fields. Known roles are always present
+func (rec *Record) RolesMap() map[string]uint64 {
+	m := make(map[string]uint64, len(AttendeeRoles))
+	for _, r := range AttendeeRoles {
+		m[fmt.Sprintf("%s_participants", r)] = rec.Roles[r]
+	}
+
+	return m
+}
+
+// RecordingStatesMap returns the recordings per lifecycle state as <state>_recordings fields. Published recordings
+// are already reported by the published_recordings field
+func (rec *Record) RecordingStatesMap() map[string]uint64 {
+	m := make(map[string]uint64, len(RecordingStates))
+	for _, s := range RecordingStates {
+		if s == "published" {
+			continue
+		}
+		m[fmt.Sprintf("%s_recordings", s)] = rec.RecordingStates[s]
+	}
+
+	return m
+}
+
+// clientTypeKey normalizes a BigBlueButton client type (HTML5, DIAL-IN, ...) into a field name prefix
+func clientTypeKey(clientType string) string {
+	if clientType == "" {
+		return "unknown"
+	}
+
+	return strings.ToLower(strings.NewReplacer("-", "_", " ", "_").Replace(clientType))
+}
+
 // ComputeMeetingMetrics perform a computation and update the record from the meeting values
 func (rec *Record) ComputeMeetingMetrics(ms []Meeting) {
 	if len(ms) == 0 {
@@ -66,25 +151,52 @@ func (rec *Record) ComputeMeetingMetrics(ms []Meeting) {
 		rec.ListenerParticipants += m.ListenerCount
 		rec.VoiceParticipants += m.VoiceParticipantCount
 		rec.VideoParticipants += m.VideoCount
+		rec.Moderators += m.Moderators()
 		if m.Recording {
 			rec.ActiveRecordings++
 		}
+		for _, a := range m.Attendees {
+			rec.ClientTypes[clientTypeKey(a.ClientType)]++
+			if a.Role != "" {
+				rec.Roles[strings.ToLower(a.Role)]++
+			}
+		}
 	}
 }
 
 // ComputeRecordingMetrics perform a computation and update the record from the meeting values
 func (rec *Record) ComputeRecordingMetrics(rs []Recording) {
-	if len(rs) == 0 {
-		return
+	for _, r := range rs {
+		rec.AddRecording(r)
 	}
+}
 
-	rec.Recordings = uint64(len(rs))
-	for _, r := range rs {
-		if r.Published {
-			rec.PublishedRecordings++
+// AddRecording updates the record recording metrics with a single recording
+func (rec *Record) AddRecording(r Recording) {
+	rec.Recordings++
+	if r.Published {
+		rec.PublishedRecordings++
+	}
+	if r.State != "" {
+		rec.RecordingStates[r.State]++
+	}
+	for _, p := range r.Playback {
+		if p.Type != "" {
+			rec.PlaybackFormats[clientTypeKey(p.Type)]++
 		}
 	}
+}
 
+// AddRecordingMetrics adds the recording metrics of another record, such as a RecordingsResponse summary
+func (rec *Record) AddRecordingMetrics(o *Record) {
+	rec.Recordings += o.Recordings
+	rec.PublishedRecordings += o.PublishedRecordings
+	for state, count := range o.RecordingStates {
+		rec.RecordingStates[state] += count
+	}
+	for format, count := range o.PlaybackFormats {
+		rec.PlaybackFormats[format] += count
+	}
 }
 
 // ComputeOnlineMetric perform a computation and update the record from the meeting values
@@ -93,3 +205,74 @@ func (rec *Record) ComputeOnlineMetric(h HealthCheck) {
 		rec.Online = 1
 	}
 }
+
+// RecordingStateCounts returns the number of recordings per lifecycle state. Known states are always present
+func RecordingStateCounts(rs []Recording) map[string]uint64 {
+	counts := make(map[string]uint64, len(RecordingStates))
+	for _, s := range RecordingStates {
+		counts[s] = 0
+	}
+
+	for _, r := range rs {
+		if r.State == "" {
+			continue
+		}
+		counts[r.State]++
+	}
+
+	return counts
+}
+
+// RecordingAges lists the recording age buckets, computed from the recording start time
+var RecordingAges = []string{"this_month", "1_6_months", "over_6_months"}
+
+// RecordingAgeCounts returns the number of recordings per age bucket. Buckets are always present
+func RecordingAgeCounts(rs []Recording, now time.Time) map[string]uint64 {
+	counts := make(map[string]uint64, len(RecordingAges))
+	for _, a := range RecordingAges {
+		counts[a] = 0
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	sixMonthsAgo := now.AddDate(0, -6, 0)
+	for _, r := range rs {
+		if r.StartTime == 0 {
+			continue
+		}
+
+		start := time.Unix(0, int64(r.StartTime)*int64(time.Millisecond))
+		switch {
+		case !start.Before(monthStart):
+			counts["this_month"]++
+		case !start.Before(sixMonthsAgo):
+			counts["1_6_months"]++
+		default:
+			counts["over_6_months"]++
+		}
+	}
+
+	return counts
+}
+
+// GuestPolicies lists the BigBlueButton meeting guest policies
+var GuestPolicies = []string{"ALWAYS_ACCEPT", "ASK_MODERATOR", "ALWAYS_DENY"}
+
+// GuestPolicyCounts returns the number of meetings per guest policy. Known policies are always present, meetings
+// whose policy is not reported by the server are counted as unknown
+func GuestPolicyCounts(ms []Meeting) map[string]uint64 {
+	counts := make(map[string]uint64, len(GuestPolicies)+1)
+	for _, p := range GuestPolicies {
+		counts[p] = 0
+	}
+	counts["unknown"] = 0
+
+	for _, m := range ms {
+		if m.GuestPolicy == "" {
+			counts["unknown"]++
+			continue
+		}
+		counts[m.GuestPolicy]++
+	}
+
+	return counts
+}
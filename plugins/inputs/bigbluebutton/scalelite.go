@@ -0,0 +1,125 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/influxdata/telegraf"
+)
+
+var scalelitePathPrefix = "/scalelite"
+
+// ScaleliteServersResponse is Scalelite XML getServers api response type
+type ScaleliteServersResponse struct {
+	XMLName    xml.Name          `xml:"response"`
+	ReturnCode string            `xml:"returncode"`
+	MessageKey string            `xml:"messageKey"`
+	Servers    []ScaleliteServer `xml:"servers>server"`
+}
+
+// ScaleliteServer is a BigBlueButton server registered in Scalelite
+type ScaleliteServer struct {
+	XMLName        xml.Name `xml:"server"`
+	ID             string   `xml:"serverID"`
+	URL            string   `xml:"serverURL"`
+	State          string   `xml:"state"`
+	Enabled        bool     `xml:"enabled"`
+	Online         bool     `xml:"online"`
+	Load           float64  `xml:"load"`
+	LoadMultiplier float64  `xml:"loadMultiplier"`
+	Meetings       uint64   `xml:"meetings"`
+}
+
+// state returns the server state, derived from enabled on Scalelite versions not reporting it
+func (s ScaleliteServer) state() string {
+	if s.State != "" {
+		return s.State
+	}
+	if s.Enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// getScaleliteServersURL returns the Scalelite getServers url. It is not affected by the BigBlueButton path prefix
+func (b *BigBlueButton) getScaleliteServersURL() string {
+	u := *b.serverURL
+	u.Path = path.Join("/", u.Path, scalelitePathPrefix, "api", "getServers")
+	u.RawQuery = fmt.Sprintf("checksum=%x", b.checksum("getServers", ""))
+	return u.String()
+}
+
+func (b *BigBlueButton) getScaleliteServers() (*ScaleliteServersResponse, error) {
+	body, _, err := b.api(b.getScaleliteServersURL())
+	if err != nil {
+		return nil, err
+	}
+
+	var response ScaleliteServersResponse
+	if err := xml.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	if response.ReturnCode != "SUCCESS" {
+		return nil, fmt.Errorf("scalelite getServers returncode %s, messageKey %s", response.ReturnCode, response.MessageKey)
+	}
+
+	return &response, nil
+}
+
+// gatherScalelite emits one bigbluebutton_scalelite_server point per server registered in Scalelite
+func (b *BigBlueButton) gatherScalelite(acc telegraf.Accumulator) error {
+	response, err := b.getScaleliteServers()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range response.Servers {
+		state := s.state()
+
+		tags := map[string]string{
+			"server_id": s.ID,
+			"state":     state,
+		}
+		if u, err := url.Parse(s.URL); err == nil && u.Host != "" {
+			tags["server"] = u.Host
+		}
+
+		fields := map[string]interface{}{
+			"load":            s.Load,
+			"load_multiplier": s.LoadMultiplier,
+			"meetings":        s.Meetings,
+			"online":          boolToUint64(s.Online),
+			"enabled":         boolToUint64(state == "enabled"),
+			"cordoned":        boolToUint64(state == "cordoned"),
+		}
+		acc.AddFields("bigbluebutton_scalelite_server", fields, tags)
+	}
+
+	return nil
+}
+
+// balancerStates returns the balancer_url servers states indexed by host. On error, the servers are gathered
+// without balancer_state
+func (b *BigBlueButton) balancerStates(acc telegraf.Accumulator) map[string]string {
+	if b.balancer == nil {
+		return nil
+	}
+
+	response, err := b.balancer.getScaleliteServers()
+	if err != nil {
+		acc.AddError(fmt.Errorf("getting balancer servers: %s", err))
+		return nil
+	}
+
+	states := make(map[string]string, len(response.Servers))
+	for _, s := range response.Servers {
+		if u, err := url.Parse(s.URL); err == nil && u.Host != "" {
+			states[u.Host] = s.state()
+		}
+	}
+	return states
+}
@@ -0,0 +1,136 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadSecretKeyFile reads the secret key from secret_key_file, surrounding whitespace trimmed. Environment variables
+// in the path are expanded. It returns true when the secret key changed
+func (b *BigBlueButton) loadSecretKeyFile() (bool, error) {
+	content, err := os.ReadFile(os.ExpandEnv(b.SecretKeyFile))
+	if err != nil {
+		return false, err
+	}
+
+	secret := strings.TrimSpace(string(content))
+	if secret == "" {
+		return false, fmt.Errorf("%s is empty", b.SecretKeyFile)
+	}
+
+	changed := secret != b.SecretKey
+	b.SecretKey = secret
+	return changed, nil
+}
+
+// reloadSecretKeyFile re-reads secret_key_file after a failed gather, so a rotated secret is picked up
+// without restarting Telegraf. Signed urls are rebuilt when the secret changed
+func (b *BigBlueButton) reloadSecretKeyFile() error {
+	changed, err := b.loadSecretKeyFile()
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		b.debugf("secret key changed in %s, signing requests with the new secret", b.SecretKeyFile)
+		b.secret = b.SecretKey
+		b.buildURLs()
+	}
+	return nil
+}
+
+// resolveSecret resolves a secret reference: "@env:NAME" reads the environment variable NAME and "@file:PATH" the
+// file at PATH, surrounding whitespace trimmed, so secrets stay out of the configuration file. Other values are
+// returned unchanged. Errors name the option and the reference, never the secret
+func resolveSecret(option string, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "@env:"):
+		name := strings.TrimPrefix(value, "@env:")
+		secret := strings.TrimSpace(os.Getenv(name))
+		if secret == "" {
+			return "", fmt.Errorf("%s: environment variable %s is not set", option, name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "@file:"):
+		path := strings.TrimPrefix(value, "@file:")
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s: %s", option, err)
+		}
+		secret := strings.TrimSpace(string(content))
+		if secret == "" {
+			return "", fmt.Errorf("%s: %s is empty", option, path)
+		}
+		return secret, nil
+	}
+
+	return value, nil
+}
+
+// resolveSecrets resolves the secret references of secret_key, secret_keys, username and password. Servers and
+// balancer secret keys are resolved by their own Init
+func (b *BigBlueButton) resolveSecrets() error {
+	options := []struct {
+		name  string
+		value *string
+	}{
+		{"secret_key", &b.SecretKey},
+		{"username", &b.Username},
+		{"password", &b.Password},
+	}
+	for i := range b.SecretKeys {
+		options = append(options, struct {
+			name  string
+			value *string
+		}{fmt.Sprintf("secret_keys[%d]", i), &b.SecretKeys[i]})
+	}
+
+	for _, o := range options {
+		secret, err := resolveSecret(o.name, *o.value)
+		if err != nil {
+			return err
+		}
+		*o.value = secret
+	}
+	return nil
+}
+
+// secretKeys returns the configured secret keys, secret_key first, then secret_keys
+func (b *BigBlueButton) secretKeys() []string {
+	keys := []string{}
+	for _, key := range append([]string{b.SecretKey}, b.SecretKeys...) {
+		if key != "" && !contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// rotateSecretKey tries the other secret keys after getMeetings rejected the checksum, and keeps signing with
+// the first one accepted. It returns false when every secret key was rejected
+func (b *BigBlueButton) rotateSecretKey() bool {
+	current := b.secret
+	for i, key := range b.secretKeys() {
+		if key == current {
+			continue
+		}
+
+		b.secret = key
+		b.buildURLs()
+		_, err := b.getMeetings()
+		if err == nil {
+			b.debugf("checksum rejected, signing requests with secret key #%d", i+1)
+			return true
+		}
+		if !isAuthError(err) {
+			break
+		}
+	}
+
+	b.secret = current
+	b.buildURLs()
+	return false
+}
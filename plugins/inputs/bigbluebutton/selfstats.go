@@ -0,0 +1,65 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// selfStats are the plugin own statistics, reported by the Telegraf internal input as the internal_bigbluebutton
+// measurement, one series per server
+type selfStats struct {
+	apiRequests      selfstat.Stat
+	httpErrors       selfstat.Stat
+	parseErrors      selfstat.Stat
+	meetingsParsed   selfstat.Stat
+	recordingsParsed selfstat.Stat
+	gatherTime       selfstat.Stat
+}
+
+// newSelfStats registers the statistics of a server. Statistics already registered for the server are reused
+func newSelfStats(server string) *selfStats {
+	tags := map[string]string{"server": server}
+	return &selfStats{
+		apiRequests:      selfstat.Register("bigbluebutton", "api_requests", tags),
+		httpErrors:       selfstat.Register("bigbluebutton", "http_errors", tags),
+		parseErrors:      selfstat.Register("bigbluebutton", "parse_errors", tags),
+		meetingsParsed:   selfstat.Register("bigbluebutton", "meetings_parsed", tags),
+		recordingsParsed: selfstat.Register("bigbluebutton", "recordings_parsed", tags),
+		gatherTime:       selfstat.RegisterTiming("bigbluebutton", "gather_time_ns", tags),
+	}
+}
+
+// request counts an api request, and its failure when the server could not be reached or did not answer 200.
+// Statistics are not recorded by plugins gathering several servers, only by the per server plugins
+func (s *selfStats) request(failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.apiRequests.Incr(1)
+	if failed {
+		s.httpErrors.Incr(1)
+	}
+}
+
+// parsed counts the decoded meetings and recordings, and the responses or entries that could not be decoded
+func (s *selfStats) parsed(meetings int, recordings int, parseErrors uint64) {
+	if s == nil {
+		return
+	}
+
+	s.meetingsParsed.Incr(int64(meetings))
+	s.recordingsParsed.Incr(int64(recordings))
+	s.parseErrors.Incr(int64(parseErrors))
+}
+
+// gathered records a gather duration
+func (s *selfStats) gathered(d time.Duration) {
+	if s == nil {
+		return
+	}
+
+	s.gatherTime.Incr(d.Nanoseconds())
+}
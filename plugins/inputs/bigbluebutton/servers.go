@@ -0,0 +1,215 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Server is a BigBlueButton server configuration used when gathering multiple servers from one plugin instance
+type Server struct {
+	Name         string   `toml:"name"`
+	URL          string   `toml:"url"`
+	SecretKey    string   `toml:"secret_key"`
+	PathPrefix   string   `toml:"path_prefix"`
+	PathPrefixes []string `toml:"path_prefixes"`
+	// BalancerState is deprecated, balancer states are discovered from balancer_url
+	BalancerState string `toml:"balancer_state"`
+}
+
+// tag returns the server tag value, the server name if set, its url otherwise
+func (s Server) tag() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.URL
+}
+
+// tags returns the tags added to every metric of the server
+func (s Server) tags() map[string]string {
+	return map[string]string{"server": s.tag()}
+}
+
+// gatheredServer is a configured server with its own plugin state
+type gatheredServer struct {
+	tags   map[string]string
+	plugin *BigBlueButton
+}
+
+// serverSet holds the gathered servers. The server list is an immutable snapshot replaced atomically, so that
+// readers never see a partially updated list. mu serializes the writers
+type serverSet struct {
+	mu sync.Mutex
+	v  atomic.Value
+}
+
+// load returns the current servers snapshot. The returned slice must not be modified
+func (s *serverSet) load() []*gatheredServer {
+	if s == nil {
+		return nil
+	}
+	servers, _ := s.v.Load().([]*gatheredServer)
+	return servers
+}
+
+// store replaces the servers snapshot
+func (s *serverSet) store(servers []*gatheredServer) {
+	s.v.Store(servers)
+}
+
+// initServers initializes one plugin per configured server. Servers inherit the plugin options
+// and the plugin secret key when they do not define their own
+func (b *BigBlueButton) initServers() error {
+	b.balancer = nil
+	if b.BalancerURL != "" {
+		// the balancer url is the Scalelite root, getServers is not affected by the BigBlueButton path prefix
+		balancer, err := b.newGatheredServer(Server{Name: "balancer", URL: b.BalancerURL, SecretKey: b.BalancerSecretKey, PathPrefix: "/"})
+		if err != nil {
+			return err
+		}
+		b.balancer = balancer.plugin
+	}
+
+	if err := b.setServers(b.Servers); err != nil {
+		return err
+	}
+
+	// servers are checked here rather than by their own Init, the balancer is not a BigBlueButton server
+	if b.StartupCheck {
+		for _, s := range b.servers.load() {
+			if err := s.plugin.startupCheck(); err != nil {
+				return fmt.Errorf("server %s: %s", s.tags["server"], err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setServers replaces the gathered servers with the configured ones. Servers whose url and secret are unchanged
+// keep their state. On error, the current servers are kept. Concurrent calls are serialized
+func (b *BigBlueButton) setServers(servers []Server) error {
+	if b.servers == nil {
+		b.servers = &serverSet{}
+	}
+	b.servers.mu.Lock()
+	defer b.servers.mu.Unlock()
+
+	known := b.knownServers()
+	gathered := make([]*gatheredServer, 0, len(servers))
+	for _, s := range servers {
+		server, err := b.reuseOrNewGatheredServer(known, s)
+		if err != nil {
+			return err
+		}
+		gathered = append(gathered, server)
+	}
+
+	b.servers.store(gathered)
+	return nil
+}
+
+// knownServers indexes the current servers by url
+func (b *BigBlueButton) knownServers() map[string]*gatheredServer {
+	known := map[string]*gatheredServer{}
+	for _, s := range b.servers.load() {
+		known[s.plugin.URL] = s
+	}
+	return known
+}
+
+// reuseOrNewGatheredServer returns the known server when its url, secret and tags are unchanged,
+// a newly initialized one otherwise
+func (b *BigBlueButton) reuseOrNewGatheredServer(known map[string]*gatheredServer, s Server) (*gatheredServer, error) {
+	secret := s.SecretKey
+	if secret == "" {
+		secret = b.SecretKey
+	}
+	// secret references are compared once resolved, a server whose referenced secret changed is initialized again
+	if resolved, err := resolveSecret("secret_key", secret); err == nil {
+		secret = resolved
+	}
+
+	if k, ok := known[s.URL]; ok && k.plugin.SecretKey == secret && equalTags(k.tags, s.tags()) {
+		return k, nil
+	}
+
+	return b.newGatheredServer(s)
+}
+
+func equalTags(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// newGatheredServer initializes a plugin for the server, inheriting the plugin options
+func (b *BigBlueButton) newGatheredServer(s Server) (*gatheredServer, error) {
+	plugin := *b
+	plugin.Servers = nil
+	plugin.servers = nil
+	plugin.balancer = nil
+	// state is not shared with the parent plugin
+	plugin.client = nil
+	plugin.endpoints = nil
+	plugin.webhooks = nil
+	plugin.recordings = nil
+	plugin.uniqueUsers = nil
+	plugin.hourlyProfile = nil
+	plugin.lastRecordings = nil
+	plugin.BigBlueSwarmURL = ""
+	// collectors run once by the parent plugin, or not per server
+	plugin.ScaleliteMode = false
+	plugin.StartupCheck = false
+	plugin.DebugMemoryStats = false
+	plugin.Probe = false
+	plugin.WebhooksListen = ""
+	plugin.Backfill = false
+	plugin.URL = s.URL
+	plugin.PathPrefix = s.PathPrefix
+	plugin.PathPrefixes = s.PathPrefixes
+	if s.SecretKey != "" {
+		plugin.SecretKey = s.SecretKey
+		plugin.SecretKeyFile = ""
+		plugin.SecretKeys = nil
+	}
+
+	if err := plugin.Init(); err != nil {
+		return nil, fmt.Errorf("server %s: %s", s.tag(), err)
+	}
+
+	return &gatheredServer{tags: s.tags(), plugin: &plugin}, nil
+}
+
+// gatherServers gathers all configured servers concurrently. Every metric is tagged with its server, and with its
+// balancer state when balancer_url is set
+func (b *BigBlueButton) gatherServers(acc telegraf.Accumulator) {
+	states := b.balancerStates(acc)
+	var wg sync.WaitGroup
+	for _, s := range b.servers.load() {
+		wg.Add(1)
+		go func(s *gatheredServer) {
+			defer wg.Done()
+			tags := s.tags
+			if state, ok := states[s.plugin.serverURL.Host]; ok {
+				tags = make(map[string]string, len(s.tags)+1)
+				for k, v := range s.tags {
+					tags[k] = v
+				}
+				tags["balancer_state"] = state
+			}
+			serverAcc := &taggedAccumulator{Accumulator: acc, tags: tags}
+			serverAcc.AddError(s.plugin.Gather(serverAcc))
+		}(s)
+	}
+	wg.Wait()
+}
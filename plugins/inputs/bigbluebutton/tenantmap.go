@@ -0,0 +1,70 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/toml"
+)
+
+// loadTenantMap reads the tenant_map_file when it changed since the previous read. The file maps, per metadata key,
+// raw metadata values to friendly names. Files with a .json extension are read as JSON, others as TOML.
+// On error, the previously read mapping is kept
+func (b *BigBlueButton) loadTenantMap() error {
+	info, err := os.Stat(b.TenantMapFile)
+	if err != nil {
+		return err
+	}
+
+	if b.tenantMap != nil && info.ModTime().Equal(b.tenantMapModTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(b.TenantMapFile)
+	if err != nil {
+		return err
+	}
+
+	mapping := map[string]map[string]string{}
+	if filepath.Ext(b.TenantMapFile) == ".json" {
+		err = json.Unmarshal(data, &mapping)
+	} else {
+		err = toml.Unmarshal(data, &mapping)
+	}
+	if err != nil {
+		return err
+	}
+
+	// keys are looked up as normalized metadata keys
+	b.tenantMap = make(map[string]map[string]string, len(mapping))
+	for key, names := range mapping {
+		b.tenantMap[b.normalizeMetadataKey(key)] = names
+	}
+	b.tenantMapModTime = info.ModTime()
+	return nil
+}
+
+// tenantName returns the friendly name of a metadata key value found in the tenant map, the value itself otherwise.
+// Combined metadata values are mapped value by value. Names are applied once values passed metadata_include and
+// metadata_exclude, which match raw values
+func (b *BigBlueButton) tenantName(key string, val string) string {
+	if len(b.tenantMap) == 0 {
+		return val
+	}
+
+	keys := strings.Split(key, combinedMetadataSeparator)
+	values := strings.Split(val, combinedMetadataSeparator)
+	if len(keys) != len(values) {
+		return val
+	}
+	for i, k := range keys {
+		if name, ok := b.tenantMap[k][values[i]]; ok {
+			values[i] = name
+		}
+	}
+
+	return strings.Join(values, combinedMetadataSeparator)
+}
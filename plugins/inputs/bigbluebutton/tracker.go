@@ -0,0 +1,54 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import "time"
+
+// recordingTracker keeps the recordings state between gathers
+type recordingTracker struct {
+	states map[string]string
+	// vanished contains recordings that disappeared while processing, with the time they disappeared
+	vanished      map[string]time.Time
+	failureWindow time.Duration
+}
+
+func newRecordingTracker(failureWindow time.Duration) *recordingTracker {
+	return &recordingTracker{
+		states:        map[string]string{},
+		vanished:      map[string]time.Time{},
+		failureWindow: failureWindow,
+	}
+}
+
+// Update stores the current recordings states and returns the number of recordings that were published since the previous update
+// and the number of recordings that disappeared while processing without being published within the failure window
+func (t *recordingTracker) Update(rs []Recording, now time.Time) (published uint64, failed uint64) {
+	states := make(map[string]string, len(rs))
+	for _, r := range rs {
+		states[r.RecordID] = r.State
+		_, wasVanished := t.vanished[r.RecordID]
+		if r.State == "published" && (isProcessingState(t.states[r.RecordID]) || wasVanished) {
+			published++
+		}
+		delete(t.vanished, r.RecordID)
+	}
+
+	for id, state := range t.states {
+		if _, ok := states[id]; !ok && isProcessingState(state) {
+			t.vanished[id] = now
+		}
+	}
+
+	for id, since := range t.vanished {
+		if now.Sub(since) >= t.failureWindow {
+			failed++
+			delete(t.vanished, id)
+		}
+	}
+
+	t.states = states
+	return published, failed
+}
+
+func isProcessingState(state string) bool {
+	return state == "processing" || state == "processed"
+}
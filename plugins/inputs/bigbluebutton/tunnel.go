@@ -0,0 +1,165 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshTunnel dials BigBlueButton connections through an SSH jump host. The SSH connection is opened lazily
+// and re-opened when it breaks
+type sshTunnel struct {
+	host   string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// newSSHTunnel creates the tunnel from the plugin ssh_tunnel_* configuration
+func (b *BigBlueButton) newSSHTunnel() (*sshTunnel, error) {
+	key, err := os.ReadFile(b.SSHTunnelKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading ssh tunnel key: %s", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ssh tunnel key: %s", err)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case b.SSHTunnelKnownHosts != "":
+		hostKeyCallback, err = knownhosts.New(b.SSHTunnelKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("reading ssh tunnel known hosts: %s", err)
+		}
+	case b.SSHTunnelInsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("ssh_tunnel_known_hosts is required unless ssh_tunnel_insecure_ignore_host_key is enabled")
+	}
+
+	host := b.SSHTunnelHost
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	// the jump host connection is bounded by connect_timeout, or timeout when not set
+	timeout := time.Duration(b.ConnectTimeout)
+	if timeout <= 0 {
+		timeout = time.Duration(b.Timeout)
+	}
+	if timeout <= 0 {
+		timeout = time.Duration(defaultTimeout)
+	}
+
+	return &sshTunnel{
+		host: host,
+		config: &ssh.ClientConfig{
+			User:            b.SSHTunnelUser,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         timeout,
+		},
+	}, nil
+}
+
+// DialContext opens a connection to addr through the jump host. It returns when ctx is done, even if the jump host
+// doesn't answer
+func (t *sshTunnel) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	client, err := t.sshClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type dialed struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan dialed, 1)
+	go func() {
+		conn, err := client.Dial(network, addr)
+		done <- dialed{conn, err}
+	}()
+
+	select {
+	case d := <-done:
+		if d.err != nil {
+			// the ssh connection may be broken, reconnect on next dial
+			t.reset(client)
+			return nil, d.err
+		}
+		return d.conn, nil
+	case <-ctx.Done():
+		go func() {
+			if d := <-done; d.conn != nil {
+				d.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (t *sshTunnel) sshClient(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	client, err := t.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("connecting ssh tunnel to %s: %s", t.host, err)
+	}
+	t.client = client
+
+	return client, nil
+}
+
+// dial connects to the jump host. The TCP connection and the ssh handshake are bounded by the config timeout and ctx,
+// so an unreachable jump host doesn't hold the tunnel lock
+func (t *sshTunnel) dial(ctx context.Context) (*ssh.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancel()
+
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", t.host)
+	if err != nil {
+		return nil, err
+	}
+
+	// closing the connection interrupts the handshake
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.host, t.config)
+	if !stop() {
+		if err == nil {
+			c.Close()
+		}
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == client {
+		t.client.Close()
+		t.client = nil
+	}
+}
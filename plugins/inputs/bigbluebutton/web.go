@@ -0,0 +1,98 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+// webMetric is a bbb-web Spring Boot actuator metric reported in the bigbluebutton_web measurement
+type webMetric struct {
+	field  string
+	metric string
+	tag    string
+}
+
+// webMetrics lists the actuator metrics gathered when web_management_url is set.
+// Metrics unknown to the actuator, such as Tomcat metrics on other containers, are skipped
+var webMetrics = []webMetric{
+	{field: "heap_used_bytes", metric: "jvm.memory.used", tag: "area:heap"},
+	{field: "heap_max_bytes", metric: "jvm.memory.max", tag: "area:heap"},
+	{field: "threads_live", metric: "jvm.threads.live"},
+	{field: "tomcat_threads_busy", metric: "tomcat.threads.busy"},
+	{field: "tomcat_threads_current", metric: "tomcat.threads.current"},
+	{field: "tomcat_threads_max", metric: "tomcat.threads.config.max"},
+}
+
+// actuatorMetric is a Spring Boot actuator metric response
+type actuatorMetric struct {
+	Name         string `json:"name"`
+	Measurements []struct {
+		Statistic string  `json:"statistic"`
+		Value     float64 `json:"value"`
+	} `json:"measurements"`
+}
+
+// getWebMetric returns the VALUE statistic of an actuator metric. ok is false when the actuator does not know the metric
+func (b *BigBlueButton) getWebMetric(m webMetric) (value float64, ok bool, err error) {
+	u := strings.TrimSuffix(b.WebManagementURL, "/") + "/metrics/" + m.metric
+	if m.tag != "" {
+		u += "?tag=" + m.tag
+	}
+
+	resp, err := b.client.Load().Get(u)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, false, nil
+	}
+	if resp.StatusCode != 200 {
+		return 0, false, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var metric actuatorMetric
+	if err := json.Unmarshal(body, &metric); err != nil {
+		return 0, false, err
+	}
+
+	for _, measurement := range metric.Measurements {
+		if measurement.Statistic == "VALUE" {
+			return measurement.Value, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// gatherWeb emits bbb-web JVM and Tomcat stats in the bigbluebutton_web measurement
+func (b *BigBlueButton) gatherWeb(acc telegraf.Accumulator) error {
+	fields := map[string]interface{}{}
+	for _, m := range webMetrics {
+		value, ok, err := b.getWebMetric(m)
+		if err != nil {
+			return fmt.Errorf("getting bbb-web metric %s: %s", m.metric, err)
+		}
+		if ok {
+			fields[m.field] = value
+		}
+	}
+
+	if len(fields) > 0 {
+		acc.AddFields("bigbluebutton_web", fields, map[string]string{})
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package bigbluebutton provides gather functionality
+package bigbluebutton
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+var defaultWebhooksPath = "/webhooks"
+
+// webhooksMaxBodySize bounds the callback body read, bbb-webhooks sends one small batch of events per callback
+const webhooksMaxBodySize = 1 << 20
+
+// webhooksReadHeaderTimeout closes connections of clients not sending their request headers in time
+const webhooksReadHeaderTimeout = 10 * time.Second
+
+// webhookEvents are the bbb-webhooks events always reported, even before being received
+var webhookEvents = []string{"meeting-created", "meeting-ended", "user-joined", "user-left"}
+
+// webhookEvent is a bbb-webhooks callback event
+type webhookEvent struct {
+	Data struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"data"`
+}
+
+// webhooks counts the bbb-webhooks events received since the plugin started
+type webhooks struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+	server   *http.Server
+	// token is the shared token callbacks must send in their token query parameter
+	token string
+}
+
+func newWebhooks(token string) *webhooks {
+	counters := make(map[string]uint64)
+	for _, e := range webhookEvents {
+		counters[e] = 0
+	}
+	return &webhooks{counters: counters, token: token}
+}
+
+// Start starts the bbb-webhooks callback listener when webhooks_listen is set
+func (b *BigBlueButton) Start(acc telegraf.Accumulator) error {
+	if b.WebhooksListen == "" {
+		return nil
+	}
+
+	if b.WebhooksToken == "" {
+		return errors.New("webhooks_token is required with webhooks_listen")
+	}
+
+	if b.WebhooksPath == "" {
+		b.WebhooksPath = defaultWebhooksPath
+	}
+
+	listener, err := net.Listen("tcp", b.WebhooksListen)
+	if err != nil {
+		return fmt.Errorf("webhooks listener: %s", err)
+	}
+
+	b.webhooks = newWebhooks(b.WebhooksToken)
+	mux := http.NewServeMux()
+	mux.HandleFunc(b.WebhooksPath, b.webhooks.ServeHTTP)
+	b.webhooks.server = &http.Server{Handler: mux, ReadHeaderTimeout: webhooksReadHeaderTimeout}
+
+	go func() {
+		if err := b.webhooks.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			acc.AddError(fmt.Errorf("webhooks listener: %s", err))
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the bbb-webhooks callback listener
+func (b *BigBlueButton) Stop() {
+	if b.webhooks != nil {
+		b.webhooks.server.Close()
+	}
+}
+
+// ServeHTTP counts the events of a bbb-webhooks callback. Events are sent in the event form value,
+// or as the request body when the callback content type is JSON. Callbacks without the shared token are rejected
+func (w *webhooks) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(req.URL.Query().Get("token")), []byte(w.token)) != 1 {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	req.Body = http.MaxBytesReader(rw, req.Body, webhooksMaxBodySize)
+	var payload []byte
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		payload = body
+	} else {
+		payload = []byte(req.FormValue("event"))
+	}
+
+	var events []webhookEvent
+	if err := json.Unmarshal(payload, &events); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.mu.Lock()
+	for _, e := range events {
+		if isCountedWebhookEvent(e.Data.ID) {
+			w.counters[e.Data.ID]++
+		}
+	}
+	w.mu.Unlock()
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// isCountedWebhookEvent check if the event is a meeting, user or recording processing (rap-*) event
+func isCountedWebhookEvent(id string) bool {
+	return contains(webhookEvents, id) || strings.HasPrefix(id, "rap-")
+}
+
+// gatherWebhooks emits the bbb-webhooks events counters in the bigbluebutton_webhooks measurement
+func (b *BigBlueButton) gatherWebhooks(acc telegraf.Accumulator) {
+	b.webhooks.mu.Lock()
+	fields := make(map[string]interface{}, len(b.webhooks.counters))
+	for id, count := range b.webhooks.counters {
+		fields[strings.Replace(id, "-", "_", -1)] = count
+	}
+	b.webhooks.mu.Unlock()
+
+	acc.AddFields("bigbluebutton_webhooks", fields, map[string]string{})
+}
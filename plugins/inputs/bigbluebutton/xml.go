@@ -3,6 +3,7 @@ package bigbluebutton
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 )
 
@@ -22,3 +23,142 @@ func xmlToMap(r io.Reader) map[string]string {
 
 	return m
 }
+
+// parseMeetingsResponse decodes a getMeetings response meeting by meeting while it is read. Malformed meeting entries
+// are skipped and counted in the response ParseErrors instead of failing the whole response. Meetings metadata is
+// discarded unless keepMetadata is set
+func parseMeetingsResponse(r io.Reader, keepMetadata bool) (*MeetingsResponse, error) {
+	var response MeetingsResponse
+	d := xml.NewDecoder(r)
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			if depth == 0 {
+				return nil, err
+			}
+			// truncated document, keep what was decoded so far
+			response.ParseErrors++
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				response.XMLName = t.Name
+			case depth == 2 && t.Name.Local == "returncode":
+				if err := d.DecodeElement(&response.ReturnCode, &t); err != nil {
+					return nil, err
+				}
+				depth--
+			case depth == 2 && t.Name.Local == "messageKey":
+				if err := d.DecodeElement(&response.MessageKey, &t); err != nil {
+					return nil, err
+				}
+				depth--
+			case depth == 3 && t.Name.Local == "meeting":
+				depth--
+				var m Meeting
+				if err := d.DecodeElement(&m, &t); err != nil {
+					response.ParseErrors++
+					if !skipToEnd(d, t.Name) {
+						// truncated document, keep what was decoded so far
+						return &response, nil
+					}
+					continue
+				}
+				if !keepMetadata {
+					m.DiscardMetadata()
+				}
+				response.Meetings.Values = append(response.Meetings.Values, m)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if response.XMLName.Local != "response" {
+		return nil, fmt.Errorf("expected element type <response> but have <%s>", response.XMLName.Local)
+	}
+
+	return &response, nil
+}
+
+// skipToEnd reads tokens until the end of the element named name, after a failed DecodeElement stopped in the middle
+// of it. Returns false when the document ends before
+func skipToEnd(d *xml.Decoder, name xml.Name) bool {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if t, ok := token.(xml.EndElement); ok && t.Name == name {
+			return true
+		}
+	}
+}
+
+// parseRecordingsResponse decodes a getRecordings response recording by recording while it is read, so large
+// responses are never buffered whole. Recording metrics are summed up while decoding, recordings themselves are only
+// kept when keepRecordings is set. Recordings metadata is discarded unless keepMetadata is set
+func parseRecordingsResponse(r io.Reader, keepMetadata bool, keepRecordings bool) (*RecordingsResponse, error) {
+	response := RecordingsResponse{Summary: NewRecord()}
+	d := xml.NewDecoder(r)
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1:
+				response.XMLName = t.Name
+			case depth == 2 && t.Name.Local == "returncode":
+				if err := d.DecodeElement(&response.ReturnCode, &t); err != nil {
+					return nil, err
+				}
+				depth--
+			case depth == 2 && t.Name.Local == "messageKey":
+				if err := d.DecodeElement(&response.MessageKey, &t); err != nil {
+					return nil, err
+				}
+				depth--
+			case depth == 3 && t.Name.Local == "recording":
+				var rec Recording
+				if err := d.DecodeElement(&rec, &t); err != nil {
+					return nil, err
+				}
+				depth--
+				response.Summary.AddRecording(rec)
+				if !keepRecordings {
+					continue
+				}
+				if !keepMetadata {
+					rec.DiscardMetadata()
+				}
+				response.Recordings.Values = append(response.Recordings.Values, rec)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+
+	if response.XMLName.Local != "response" {
+		return nil, fmt.Errorf("expected element type <response> but have <%s>", response.XMLName.Local)
+	}
+
+	return &response, nil
+}